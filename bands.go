@@ -0,0 +1,162 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+)
+
+// BandBoolOperation folds the bands of each pixel into a single band
+type BandBoolOperation int
+
+const (
+	BAND_AND BandBoolOperation = C.VIPS_OPERATION_BOOLEAN_AND
+	BAND_OR  BandBoolOperation = C.VIPS_OPERATION_BOOLEAN_OR
+	BAND_EOR BandBoolOperation = C.VIPS_OPERATION_BOOLEAN_EOR
+)
+
+// ExtractBand keeps n bands of the image from the given band index,
+// such as 0 for the red band or 3 for the alpha band of RGBA images.
+// Images of one or two bands are saved as greyscale. Like every band
+// operation, JPEG images of 2 or 4 bands are converted to PNG
+func ExtractBand(buf []byte, band, n int) ([]byte, error) {
+	return transformBands(buf, func(image *C.VipsImage) (*C.VipsImage, error) {
+		if band < 0 || n < 1 || band+n > int(image.Bands) {
+			C.g_object_unref(C.gpointer(image))
+			return nil, errors.New("Invalid image band")
+		}
+		return vipsExtractBand(image, band, n)
+	})
+}
+
+// BandJoin joins the bands of the images, in order, into a single image
+// of the type of the first one. Smaller images are extended with black
+// pixels to the size of the largest one
+func BandJoin(bufs ...[]byte) ([]byte, error) {
+	if len(bufs) == 0 {
+		return nil, errors.New("Image buffer is empty")
+	}
+
+	defer C.vips_thread_shutdown()
+
+	images := make([]*C.VipsImage, 0, len(bufs))
+	defer func() {
+		for _, image := range images {
+			C.g_object_unref(C.gpointer(image))
+		}
+	}()
+
+	var imageType ImageType
+	for i, buf := range bufs {
+		image, t, err := vipsRead(buf)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			imageType = t
+		}
+		images = append(images, image)
+	}
+
+	image, err := vipsBandJoin(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return saveBands(image, imageType)
+}
+
+// ReorderBands joins the bands of the image in the given order,
+// such as 2, 1, 0 to swap RGB and BGR. Bands can be skipped or repeated
+func ReorderBands(buf []byte, order ...int) ([]byte, error) {
+	return transformBands(buf, func(image *C.VipsImage) (*C.VipsImage, error) {
+		if len(order) == 0 {
+			C.g_object_unref(C.gpointer(image))
+			return nil, errors.New("Band order is empty")
+		}
+		for _, band := range order {
+			if band < 0 || band >= int(image.Bands) {
+				C.g_object_unref(C.gpointer(image))
+				return nil, errors.New("Invalid image band")
+			}
+		}
+		return vipsReorderBands(image, order)
+	})
+}
+
+// BandBool folds the bands of the image with a bitwise operation,
+// returning a greyscale image
+func BandBool(buf []byte, operation BandBoolOperation) ([]byte, error) {
+	return transformBands(buf, func(image *C.VipsImage) (*C.VipsImage, error) {
+		return vipsBandBool(image, operation)
+	})
+}
+
+// Keep n bands of the image from the given band index
+func (i *Image) ExtractBand(band, n int) ([]byte, error) {
+	return i.updateBands(ExtractBand(i.buffer, band, n))
+}
+
+// Join the bands of the given images after the bands of the image
+func (i *Image) BandJoin(bufs ...[]byte) ([]byte, error) {
+	return i.updateBands(BandJoin(append([][]byte{i.buffer}, bufs...)...))
+}
+
+// Reorder the bands of the image, such as 2, 1, 0 for RGB to BGR
+func (i *Image) ReorderBands(order ...int) ([]byte, error) {
+	return i.updateBands(ReorderBands(i.buffer, order...))
+}
+
+// Fold the bands of the image with a bitwise operation
+func (i *Image) BandBool(operation BandBoolOperation) ([]byte, error) {
+	return i.updateBands(BandBool(i.buffer, operation))
+}
+
+func (i *Image) updateBands(buf []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	i.buffer = buf
+	return buf, nil
+}
+
+func transformBands(buf []byte, transform func(image *C.VipsImage) (*C.VipsImage, error)) ([]byte, error) {
+	defer C.vips_thread_shutdown()
+
+	image, imageType, err := vipsRead(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err = transform(image)
+	if err != nil {
+		return nil, err
+	}
+
+	return saveBands(image, imageType)
+}
+
+// saveBands encodes the image in the given type, keeping its bands.
+// Types without alpha channel, such as JPEG, would flatten the last band
+// of 2 or 4 bands images, which are saved as PNG instead, like AddAlpha
+func saveBands(image *C.VipsImage, imageType ImageType) ([]byte, error) {
+	image, err := vipsBandsInterpretation(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if !imageTypeHasAlpha(imageType) && image.Bands != 1 && image.Bands != 3 {
+		imageType = PNG
+	}
+
+	interpretation := INTERPRETATION_sRGB
+	if image.Bands < 3 {
+		interpretation = INTERPRETATION_B_W
+	}
+
+	return vipsSave(image, vipsSaveOptions{Type: imageType, Quality: QUALITY, Compression: 6, Interpretation: interpretation})
+}
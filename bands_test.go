@@ -0,0 +1,138 @@
+package bimg
+
+import (
+	"math"
+	"testing"
+)
+
+func assertChannels(t *testing.T, buf []byte, channels int) {
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if metadata.Channels != channels {
+		t.Errorf("Invalid number of channels: %d != %d", metadata.Channels, channels)
+	}
+}
+
+func TestExtractBand(t *testing.T) {
+	buf := readImage("test.png")
+
+	red, err := ExtractBand(buf, 0, 1)
+	if err != nil {
+		t.Fatalf("Cannot extract the band: %s", err)
+	}
+	assertChannels(t, red, 1)
+	if err := assertSize(red, 400, 300); err != nil {
+		t.Error(err)
+	}
+
+	alpha, err := NewImage(buf).ExtractBand(3, 1)
+	if err != nil {
+		t.Fatalf("Cannot extract the band: %s", err)
+	}
+	assertChannels(t, alpha, 1)
+
+	if _, err := ExtractBand(buf, 3, 2); err == nil {
+		t.Error("Extracting missing bands must fail")
+	}
+
+	Write("fixtures/test_extract_band_out.png", red)
+}
+
+func TestBandsJPEG(t *testing.T) {
+	buf := readImage("test.jpg")
+
+	two, err := ExtractBand(buf, 0, 2)
+	if err != nil {
+		t.Fatalf("Cannot extract the bands: %s", err)
+	}
+	if DetermineImageType(two) != PNG {
+		t.Error("Two bands image must be converted to png")
+	}
+	assertChannels(t, two, 2)
+
+	mask, err := ExtractBand(buf, 1, 1)
+	if err != nil {
+		t.Fatalf("Cannot extract the band: %s", err)
+	}
+	if DetermineImageType(mask) != JPEG {
+		t.Error("Single band image must stay jpeg")
+	}
+
+	joined, err := BandJoin(buf, mask)
+	if err != nil {
+		t.Fatalf("Cannot join the bands: %s", err)
+	}
+	if DetermineImageType(joined) != PNG {
+		t.Error("Four bands image must be converted to png")
+	}
+	assertChannels(t, joined, 4)
+}
+
+func TestReorderBands(t *testing.T) {
+	buf := readImage("test.png")
+
+	bgr, err := NewImage(buf).ReorderBands(2, 1, 0, 3)
+	if err != nil {
+		t.Fatalf("Cannot reorder the bands: %s", err)
+	}
+	assertChannels(t, bgr, 4)
+
+	before, err := Stats(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image stats: %s", err)
+	}
+	after, err := Stats(bgr)
+	if err != nil {
+		t.Fatalf("Cannot read the image stats: %s", err)
+	}
+	if math.Abs(before.Bands[0].Mean-after.Bands[2].Mean) > 0.01 || math.Abs(before.Bands[2].Mean-after.Bands[0].Mean) > 0.01 {
+		t.Error("Red and blue bands must be swapped")
+	}
+
+	if _, err := ReorderBands(buf, 0, 4); err == nil {
+		t.Error("Reordering missing bands must fail")
+	}
+
+	Write("fixtures/test_reorder_bands_out.png", bgr)
+}
+
+func TestBandJoin(t *testing.T) {
+	buf := readImage("test.png")
+
+	var bands [][]byte
+	for band := 0; band < 3; band++ {
+		out, err := ExtractBand(buf, band, 1)
+		if err != nil {
+			t.Fatalf("Cannot extract the band: %s", err)
+		}
+		bands = append(bands, out)
+	}
+
+	image := NewImage(bands[0])
+	rgb, err := image.BandJoin(bands[1:]...)
+	if err != nil {
+		t.Fatalf("Cannot join the bands: %s", err)
+	}
+	assertChannels(t, rgb, 3)
+	if err := assertSize(rgb, 400, 300); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := BandJoin(); err == nil {
+		t.Error("Joining no image must fail")
+	}
+
+	Write("fixtures/test_band_join_out.png", rgb)
+}
+
+func TestBandBool(t *testing.T) {
+	for _, operation := range []BandBoolOperation{BAND_AND, BAND_OR, BAND_EOR} {
+		buf, err := NewImage(readImage("test.png")).BandBool(operation)
+		if err != nil {
+			t.Fatalf("Cannot fold the bands: %s", err)
+		}
+		assertChannels(t, buf, 1)
+	}
+}
@@ -0,0 +1,206 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ColorStop is the colour of a gradient at the given offset, from 0 to 1
+type ColorStop struct {
+	Offset float64
+	Color  Color
+	// Opacity from 0 to 1, opaque if zero
+	Opacity float64
+	// Fully transparent stop, regardless of Opacity
+	Transparent bool
+}
+
+// NewCanvas creates a PNG image filled with the given colour,
+// ready to be processed or composited onto. Like ColorStop and
+// Layer, opacity goes from 0 to 1 and the canvas is opaque if zero:
+// opaque canvases have no alpha channel
+func NewCanvas(width, height int, color Color, opacity float64) (*Image, error) {
+	alpha := 255.0
+	if opacity > 0 && opacity < 1 {
+		alpha = math.Floor(opacity*255 + 0.5)
+	}
+	return newCanvas(width, height, color, alpha)
+}
+
+// NewTransparentCanvas creates a fully transparent PNG image
+func NewTransparentCanvas(width, height int) (*Image, error) {
+	return newCanvas(width, height, Color{}, 0)
+}
+
+func newCanvas(width, height int, color Color, alpha float64) (*Image, error) {
+	defer C.vips_thread_shutdown()
+
+	if err := checkCanvasSize(width, height); err != nil {
+		return nil, err
+	}
+
+	colour := [4]float64{float64(color.R), float64(color.G), float64(color.B), alpha}
+	bands := 3
+	if alpha < 255 {
+		bands = 4
+	}
+
+	image, err := vipsCanvas(width, height, colour, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	return saveCanvas(image)
+}
+
+// NewLinearGradient creates a PNG image with a linear gradient along the
+// given angle in degrees: 0 goes from left to right, 90 from top to bottom.
+// Like CSS gradients, the first and last stops reach the image corners
+func NewLinearGradient(width, height int, angle float64, stops []ColorStop) (*Image, error) {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	// Length of the gradient line across the image
+	length := math.Abs(float64(width)*cos) + math.Abs(float64(height)*sin)
+
+	return newGradient(width, height, stops, false, cos, sin, length)
+}
+
+// NewRadialGradient creates a PNG image with an elliptical gradient
+// from the image centre, offset 0, to its corners, offset 1
+func NewRadialGradient(width, height int, stops []ColorStop) (*Image, error) {
+	return newGradient(width, height, stops, true, 0, 0, 0)
+}
+
+func newGradient(width, height int, stops []ColorStop, radial bool, cos, sin, length float64) (*Image, error) {
+	defer C.vips_thread_shutdown()
+
+	if err := checkCanvasSize(width, height); err != nil {
+		return nil, err
+	}
+
+	colours, bands, err := gradientLUT(stops)
+	if err != nil {
+		return nil, err
+	}
+
+	lut, err := vipsImageFromPixels(colours, len(colours)/bands, 1, bands)
+	if err != nil {
+		return nil, err
+	}
+	defer C.g_object_unref(C.gpointer(lut))
+
+	image, err := vipsGradient(lut, width, height, radial, cos, sin, length)
+	if err != nil {
+		return nil, err
+	}
+
+	return saveCanvas(image)
+}
+
+func checkCanvasSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > MAX_SIZE || height > MAX_SIZE {
+		return errors.New("Invalid canvas size")
+	}
+	return nil
+}
+
+func saveCanvas(image *C.VipsImage) (*Image, error) {
+	buf, err := vipsSave(image, vipsSaveOptions{Type: PNG, Compression: 6})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewImage(buf), nil
+}
+
+// gradientLUT renders the colour stops, interpolated at 256 offsets
+// from 0 to 1, as the colour table of the gradient image.
+// The alpha band is only added if needed
+func gradientLUT(stops []ColorStop) ([]byte, int, error) {
+	if len(stops) == 0 {
+		return nil, 0, errors.New("Gradient needs at least one colour stop")
+	}
+
+	// Premultiplied colours avoid dark fringes towards transparent stops
+	sorted := make([]ColorStop, len(stops))
+	copy(sorted, stops)
+	sort.Stable(colorStops(sorted))
+
+	colours := make([][4]float64, len(sorted))
+	bands := 3
+	for i, stop := range sorted {
+		alpha := stop.Opacity
+		if stop.Transparent {
+			alpha = 0
+		} else if alpha <= 0 || alpha > 1 {
+			alpha = 1
+		}
+		if alpha < 1 {
+			bands = 4
+		}
+		colours[i] = [4]float64{
+			float64(stop.Color.R) * alpha,
+			float64(stop.Color.G) * alpha,
+			float64(stop.Color.B) * alpha,
+			alpha,
+		}
+	}
+
+	lut := make([]byte, 256*bands)
+	for x := 0; x < 256; x++ {
+		colour := interpolateStops(sorted, colours, float64(x)/255)
+
+		i := x * bands
+		for b := 0; b < 3; b++ {
+			value := 0.0
+			if colour[3] > 0 {
+				value = colour[b] / colour[3]
+			}
+			lut[i+b] = uint8(math.Max(0, math.Min(255, math.Floor(value+0.5))))
+		}
+		if bands == 4 {
+			lut[i+3] = uint8(math.Floor(colour[3]*255 + 0.5))
+		}
+	}
+
+	return lut, bands, nil
+}
+
+func interpolateStops(stops []ColorStop, colours [][4]float64, offset float64) [4]float64 {
+	last := len(stops) - 1
+	if offset <= stops[0].Offset {
+		return colours[0]
+	}
+	if offset >= stops[last].Offset {
+		return colours[last]
+	}
+
+	i := 1
+	for stops[i].Offset < offset {
+		i++
+	}
+
+	from, to := stops[i-1].Offset, stops[i].Offset
+	if to == from {
+		return colours[i]
+	}
+
+	ratio := (offset - from) / (to - from)
+	var colour [4]float64
+	for b := range colour {
+		colour[b] = colours[i-1][b] + (colours[i][b]-colours[i-1][b])*ratio
+	}
+	return colour
+}
+
+type colorStops []ColorStop
+
+func (s colorStops) Len() int           { return len(s) }
+func (s colorStops) Less(i, j int) bool { return s[i].Offset < s[j].Offset }
+func (s colorStops) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
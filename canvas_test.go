@@ -0,0 +1,128 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestNewCanvas(t *testing.T) {
+	canvas, err := NewCanvas(320, 200, Color{255, 0, 0}, 0)
+	if err != nil {
+		t.Fatalf("Cannot create the canvas: %s", err)
+	}
+	if err := assertSize(canvas.Image(), 320, 200); err != nil {
+		t.Error(err)
+	}
+
+	metadata, err := canvas.Metadata()
+	if err != nil {
+		t.Fatalf("Cannot read the canvas metadata: %s", err)
+	}
+	if metadata.Alpha {
+		t.Error("Opaque canvas must not have an alpha channel")
+	}
+
+	// Canvases are processed like any other image
+	buf, err := canvas.Process(Options{Width: 160, Height: 100, Type: JPEG})
+	if err != nil {
+		t.Fatalf("Cannot process the canvas: %s", err)
+	}
+	if err := assertSize(buf, 160, 100); err != nil {
+		t.Error(err)
+	}
+
+	transparent, err := NewCanvas(100, 100, Color{}, 0.5)
+	if err != nil {
+		t.Fatalf("Cannot create the canvas: %s", err)
+	}
+	metadata, err = transparent.Metadata()
+	if err != nil {
+		t.Fatalf("Cannot read the canvas metadata: %s", err)
+	}
+	if !metadata.Alpha {
+		t.Error("Translucent canvas must have an alpha channel")
+	}
+
+	empty, err := NewTransparentCanvas(100, 100)
+	if err != nil {
+		t.Fatalf("Cannot create the canvas: %s", err)
+	}
+	stats, err := Stats(empty.Image())
+	if err != nil {
+		t.Fatalf("Cannot read the canvas stats: %s", err)
+	}
+	if !stats.Alpha || stats.Bands[3].Max != 0 {
+		t.Errorf("Canvas must be fully transparent: %#v", stats)
+	}
+
+	if _, err := NewCanvas(0, 100, Color{}, 1); err == nil {
+		t.Error("Empty canvas must fail")
+	}
+}
+
+func TestGradients(t *testing.T) {
+	stops := []ColorStop{
+		{Offset: 1, Color: Color{0, 0, 255}, Transparent: true},
+		{Offset: 0, Color: Color{255, 0, 0}},
+	}
+
+	linear, err := NewLinearGradient(400, 100, 0, stops)
+	if err != nil {
+		t.Fatalf("Cannot create the gradient: %s", err)
+	}
+	radial, err := NewRadialGradient(200, 200, stops)
+	if err != nil {
+		t.Fatalf("Cannot create the gradient: %s", err)
+	}
+
+	if err := assertSize(linear.Image(), 400, 100); err != nil {
+		t.Error(err)
+	}
+	if err := assertSize(radial.Image(), 200, 200); err != nil {
+		t.Error(err)
+	}
+
+	Write("fixtures/test_linear_gradient_out.png", linear.Image())
+	Write("fixtures/test_radial_gradient_out.png", radial.Image())
+}
+
+func TestGradientLUT(t *testing.T) {
+	// Stops on the entries 0, 153 and 255
+	stops := []ColorStop{
+		{Offset: 0, Color: Color{255, 0, 0}},
+		{Offset: 0.6, Color: Color{0, 255, 0}, Opacity: 0.5},
+		{Offset: 1, Color: Color{0, 0, 255}},
+	}
+
+	lut, bands, err := gradientLUT(stops)
+	if err != nil {
+		t.Fatalf("Cannot render the gradient: %s", err)
+	}
+	if bands != 4 || len(lut) != 256*4 {
+		t.Fatalf("Invalid number of bands: %d", bands)
+	}
+
+	tests := []struct {
+		x     int
+		pixel [4]uint8
+	}{
+		{0, [4]uint8{255, 0, 0, 255}},
+		{51, [4]uint8{204, 51, 0, 213}},
+		{153, [4]uint8{0, 255, 0, 128}},
+		{255, [4]uint8{0, 0, 255, 255}},
+	}
+
+	for _, test := range tests {
+		var pixel [4]uint8
+		copy(pixel[:], lut[test.x*4:])
+		for b := range pixel {
+			if diff := int(pixel[b]) - int(test.pixel[b]); diff < -1 || diff > 1 {
+				t.Errorf("Invalid entry %d: %v != %v", test.x, pixel, test.pixel)
+				break
+			}
+		}
+	}
+
+	if _, _, err := gradientLUT(nil); err == nil {
+		t.Error("Gradient without stops must fail")
+	}
+}
@@ -0,0 +1,261 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Size of the thumbnail used to analyse the image colours
+const colorSampleSize = 100
+
+// ColorCoverage is a colour with the ratio of opaque pixels it represents
+type ColorCoverage struct {
+	Color    Color
+	Coverage float64
+}
+
+// DominantColors returns up to n dominant colours of the image, most
+// represented first, quantising a shrunk copy of the image by median cut.
+// Pixels are weighted by their opacity, so transparent pixels are ignored
+func DominantColors(buf []byte, n int) ([]ColorCoverage, error) {
+	if n <= 0 {
+		return nil, errors.New("The number of colours must be positive")
+	}
+
+	samples, err := readColorSamples(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0.0
+	for _, s := range samples {
+		total += s.weight
+	}
+
+	boxes := []colorBox{samples}
+	for len(boxes) < n {
+		index, channel := -1, 0
+		score := 0.0
+		for i, box := range boxes {
+			c, r := box.widestChannel()
+			if r > 0 && r*box.weight() > score {
+				index, channel, score = i, c, r*box.weight()
+			}
+		}
+		if index == -1 {
+			break
+		}
+
+		a, b := boxes[index].split(channel)
+		boxes[index] = a
+		boxes = append(boxes, b)
+	}
+
+	colors := make([]ColorCoverage, len(boxes))
+	for i, box := range boxes {
+		colors[i] = ColorCoverage{Color: box.mean(), Coverage: box.weight() / total}
+	}
+	sort.Sort(byCoverage(colors))
+
+	return colors, nil
+}
+
+// AverageColor returns the mean colour of the image opaque pixels
+func AverageColor(buf []byte) (Color, error) {
+	samples, err := readColorSamples(buf)
+	if err != nil {
+		return Color{}, err
+	}
+	return colorBox(samples).mean(), nil
+}
+
+// Get the dominant colours of the image
+func (i *Image) DominantColors(n int) ([]ColorCoverage, error) {
+	return DominantColors(i.buffer, n)
+}
+
+// Get the average colour of the image
+func (i *Image) AverageColor() (Color, error) {
+	return AverageColor(i.buffer)
+}
+
+// Lab converts the sRGB colour to CIE L*a*b* (D65 white point)
+func (c Color) Lab() (l, a, b float64) {
+	r, g, bl := srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*bl) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*bl
+	z := (0.0193*r + 0.1192*g + 0.9505*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// ColorDistance returns the perceptual distance between two colours
+// as the CIE76 delta E of their L*a*b* values.
+// A distance below 2.3 is hardly noticeable
+func ColorDistance(x, y Color) float64 {
+	l1, a1, b1 := x.Lab()
+	l2, a2, b2 := y.Lab()
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// readRGBA reads the image scaled to the given size (see readScaledImage),
+// returning its sRGB pixels with alpha, 4 bytes per pixel
+func readRGBA(buf []byte, width, height int) ([]byte, int, int, error) {
+	defer C.vips_thread_shutdown()
+
+	image, err := readScaledImage(buf, width, height)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	image, err = vipsSRGB(image)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	pixels, err := vipsImagePixels(image)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	bands := int(image.Bands)
+	width, height = int(image.Xsize), int(image.Ysize)
+
+	rgba := make([]byte, width*height*4)
+	for i := 0; i < width*height; i++ {
+		p, out := pixels[i*bands:(i+1)*bands], rgba[i*4:(i+1)*4]
+		switch bands {
+		case 1:
+			out[0], out[1], out[2], out[3] = p[0], p[0], p[0], 255
+		case 2:
+			out[0], out[1], out[2], out[3] = p[0], p[0], p[0], p[1]
+		case 3:
+			out[0], out[1], out[2], out[3] = p[0], p[1], p[2], 255
+		default:
+			out[0], out[1], out[2], out[3] = p[0], p[1], p[2], p[3]
+		}
+	}
+
+	return rgba, width, height, nil
+}
+
+type colorSample struct {
+	rgb    [3]float64
+	weight float64
+}
+
+func readColorSamples(buf []byte) ([]colorSample, error) {
+	rgba, _, _, err := readRGBA(buf, colorSampleSize, colorSampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]colorSample, 0, len(rgba)/4)
+	for i := 0; i < len(rgba); i += 4 {
+		if rgba[i+3] == 0 {
+			continue
+		}
+		samples = append(samples, colorSample{
+			rgb:    [3]float64{float64(rgba[i]), float64(rgba[i+1]), float64(rgba[i+2])},
+			weight: float64(rgba[i+3]) / 255,
+		})
+	}
+
+	if len(samples) == 0 {
+		return nil, errors.New("The image has no opaque pixels")
+	}
+
+	return samples, nil
+}
+
+type colorBox []colorSample
+
+func (b colorBox) weight() float64 {
+	w := 0.0
+	for _, s := range b {
+		w += s.weight
+	}
+	return w
+}
+
+func (b colorBox) mean() Color {
+	var sum [3]float64
+	w := 0.0
+	for _, s := range b {
+		for c := 0; c < 3; c++ {
+			sum[c] += s.rgb[c] * s.weight
+		}
+		w += s.weight
+	}
+
+	channel := func(c int) uint8 {
+		return uint8(math.Floor(sum[c]/w + 0.5))
+	}
+	return Color{channel(0), channel(1), channel(2)}
+}
+
+func (b colorBox) widestChannel() (int, float64) {
+	channel, widest := 0, 0.0
+	for c := 0; c < 3; c++ {
+		min, max := 255.0, 0.0
+		for _, s := range b {
+			min, max = math.Min(min, s.rgb[c]), math.Max(max, s.rgb[c])
+		}
+		if max-min > widest {
+			channel, widest = c, max-min
+		}
+	}
+	return channel, widest
+}
+
+// split the box at the weighted median of the given channel
+func (b colorBox) split(channel int) (colorBox, colorBox) {
+	sort.Sort(byChannel{b, channel})
+
+	half, w := b.weight()/2, 0.0
+	index := len(b) - 1
+	for i, s := range b {
+		if w+s.weight > half {
+			index = i
+			break
+		}
+		w += s.weight
+	}
+	if index == 0 {
+		index = 1
+	}
+
+	return b[:index], b[index:]
+}
+
+type byChannel struct {
+	box     colorBox
+	channel int
+}
+
+func (s byChannel) Len() int           { return len(s.box) }
+func (s byChannel) Swap(i, j int)      { s.box[i], s.box[j] = s.box[j], s.box[i] }
+func (s byChannel) Less(i, j int) bool { return s.box[i].rgb[s.channel] < s.box[j].rgb[s.channel] }
+
+type byCoverage []ColorCoverage
+
+func (c byCoverage) Len() int           { return len(c) }
+func (c byCoverage) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byCoverage) Less(i, j int) bool { return c[i].Coverage > c[j].Coverage }
@@ -0,0 +1,77 @@
+package bimg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDominantColors(t *testing.T) {
+	files := []string{"test.jpg", "test.png", "transparent.png"}
+
+	for _, file := range files {
+		colors, err := initImage(file).DominantColors(5)
+		if err != nil {
+			t.Fatalf("Cannot extract the colours of %s: %s", file, err)
+		}
+		if len(colors) == 0 || len(colors) > 5 {
+			t.Fatalf("Invalid number of colours: %d", len(colors))
+		}
+
+		total := 0.0
+		for index, color := range colors {
+			if index > 0 && color.Coverage > colors[index-1].Coverage {
+				t.Errorf("Colours are not sorted by coverage: %#v", colors)
+			}
+			total += color.Coverage
+		}
+		if math.Abs(total-1) > 0.0001 {
+			t.Errorf("Invalid total coverage: %f", total)
+		}
+	}
+}
+
+func TestAverageColor(t *testing.T) {
+	color, err := initImage("test.jpg").AverageColor()
+	if err != nil {
+		t.Fatalf("Cannot get the average colour: %s", err)
+	}
+	if color == (Color{}) {
+		t.Errorf("Invalid average colour: %#v", color)
+	}
+}
+
+func TestColorBoxMedianCut(t *testing.T) {
+	box := colorBox{
+		{rgb: [3]float64{255, 0, 0}, weight: 1},
+		{rgb: [3]float64{250, 0, 0}, weight: 1},
+		{rgb: [3]float64{0, 0, 255}, weight: 1},
+		{rgb: [3]float64{0, 0, 250}, weight: 0.5},
+	}
+
+	channel, width := box.widestChannel()
+	if width != 255 || channel != 0 {
+		t.Fatalf("Invalid widest channel: %d (%f)", channel, width)
+	}
+
+	a, b := box.split(channel)
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("Invalid split: %d, %d", len(a), len(b))
+	}
+	if a.mean() != (Color{0, 0, 253}) || b.mean() != (Color{253, 0, 0}) {
+		t.Fatalf("Invalid box colours: %#v, %#v", a.mean(), b.mean())
+	}
+}
+
+func TestColorLab(t *testing.T) {
+	l, a, b := Color{255, 255, 255}.Lab()
+	if math.Abs(l-100) > 0.1 || math.Abs(a) > 0.1 || math.Abs(b) > 0.1 {
+		t.Errorf("Invalid white Lab: %f, %f, %f", l, a, b)
+	}
+
+	if d := ColorDistance(Color{10, 20, 30}, Color{10, 20, 30}); d != 0 {
+		t.Errorf("Invalid distance: %f", d)
+	}
+	if d := ColorDistance(Color{0, 0, 0}, Color{255, 255, 255}); math.Abs(d-100) > 0.1 {
+		t.Errorf("Invalid distance: %f", d)
+	}
+}
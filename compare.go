@@ -0,0 +1,111 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+)
+
+// CompareOptions defines how two images are compared
+type CompareOptions struct {
+	// Resize the second image to the size of the first one if they differ.
+	// Otherwise, comparing images of different sizes fails
+	Resize bool
+	// Pixels with a band absolute difference above the threshold are counted as different
+	Threshold float64
+	// Generate a heatmap image of the differences
+	Diff bool
+	// Heatmap image type, PNG by default
+	DiffType ImageType
+}
+
+// CompareResult holds the similarity metrics of two images.
+// Errors are expressed in 0-255 pixel values, over the bands present in both images.
+type CompareResult struct {
+	// Peak signal-to-noise ratio in dB, +Inf for identical images
+	PSNR float64
+	// Mean structural similarity of the luminance, 1 for identical images
+	SSIM       float64
+	MaxError   float64
+	MeanError  float64
+	DiffPixels int
+	// Heatmap of the differences, only if requested
+	Diff []byte
+}
+
+// Compare computes the similarity between two images
+func Compare(a, b []byte, o CompareOptions) (CompareResult, error) {
+	defer C.vips_thread_shutdown()
+
+	imageA, _, err := vipsRead(a)
+	if err != nil {
+		return CompareResult{}, err
+	}
+	defer C.g_object_unref(C.gpointer(imageA))
+
+	imageB, imageType, err := vipsRead(b)
+	if err != nil {
+		return CompareResult{}, err
+	}
+
+	if imageA.Xsize != imageB.Xsize || imageA.Ysize != imageB.Ysize {
+		if !o.Resize {
+			C.g_object_unref(C.gpointer(imageB))
+			return CompareResult{}, fmt.Errorf("Images have different sizes: %dx%d != %dx%d",
+				imageA.Xsize, imageA.Ysize, imageB.Xsize, imageB.Ysize)
+		}
+
+		options := Options{Width: int(imageA.Xsize), Height: int(imageA.Ysize), Force: true, NoAutoRotate: true}
+		imageB, err = processImage(imageB, nil, imageType, options)
+		if err != nil {
+			return CompareResult{}, err
+		}
+	}
+	defer C.g_object_unref(C.gpointer(imageB))
+
+	stats, diff, err := vipsCompare(imageA, imageB, o.Threshold, o.Diff)
+	if err != nil {
+		return CompareResult{}, err
+	}
+
+	ssim, err := vipsSSIM(imageA, imageB)
+	if err != nil {
+		if diff != nil {
+			C.g_object_unref(C.gpointer(diff))
+		}
+		return CompareResult{}, err
+	}
+
+	result := CompareResult{
+		PSNR:       math.Inf(1),
+		SSIM:       ssim,
+		MaxError:   stats.Max,
+		MeanError:  stats.Mean,
+		DiffPixels: stats.Pixels,
+	}
+	if stats.MSE > 0 {
+		result.PSNR = 10 * math.Log10(255*255/stats.MSE)
+	}
+
+	if diff != nil {
+		if o.DiffType == UNKNOWN {
+			o.DiffType = PNG
+		}
+		result.Diff, err = vipsSave(diff, vipsSaveOptions{Type: o.DiffType, Quality: QUALITY, Compression: 6})
+		if err != nil {
+			return CompareResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// Compare the current image with another image buffer
+func (i *Image) Compare(buf []byte, o CompareOptions) (CompareResult, error) {
+	return Compare(i.buffer, buf, o)
+}
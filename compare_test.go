@@ -0,0 +1,80 @@
+package bimg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareIdentical(t *testing.T) {
+	buf := readImage("test.jpg")
+
+	result, err := Compare(buf, buf, CompareOptions{})
+	if err != nil {
+		t.Fatalf("Cannot compare the images: %s", err)
+	}
+
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Invalid PSNR: %f", result.PSNR)
+	}
+	if result.SSIM < 0.999 {
+		t.Errorf("Invalid SSIM: %f", result.SSIM)
+	}
+	if result.MaxError != 0 || result.MeanError != 0 || result.DiffPixels != 0 {
+		t.Errorf("Invalid errors: %#v", result)
+	}
+	if result.Diff != nil {
+		t.Error("Unexpected diff image")
+	}
+}
+
+func TestCompareDifferent(t *testing.T) {
+	buf := readImage("test.jpg")
+	degraded, err := Resize(buf, Options{Quality: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewImage(buf).Compare(degraded, CompareOptions{Threshold: 10, Diff: true})
+	if err != nil {
+		t.Fatalf("Cannot compare the images: %s", err)
+	}
+
+	if math.IsInf(result.PSNR, 1) || result.PSNR <= 0 {
+		t.Errorf("Invalid PSNR: %f", result.PSNR)
+	}
+	if result.SSIM >= 1 {
+		t.Errorf("Invalid SSIM: %f", result.SSIM)
+	}
+	if result.MaxError <= 10 || result.MeanError <= 0 || result.DiffPixels == 0 {
+		t.Errorf("Invalid errors: %#v", result)
+	}
+	if DetermineImageType(result.Diff) != PNG {
+		t.Fatal("Invalid diff image")
+	}
+	if err := assertSize(result.Diff, 1680, 1050); err != nil {
+		t.Error(err)
+	}
+
+	Write("fixtures/test_compare_diff_out.png", result.Diff)
+}
+
+func TestCompareDifferentSizes(t *testing.T) {
+	buf := readImage("test.jpg")
+	small, err := Resize(buf, Options{Width: 840, Height: 525})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Compare(buf, small, CompareOptions{})
+	if err == nil {
+		t.Fatal("Expected different sizes error")
+	}
+
+	result, err := Compare(buf, small, CompareOptions{Resize: true})
+	if err != nil {
+		t.Fatalf("Cannot compare the images: %s", err)
+	}
+	if result.SSIM < 0.5 || result.PSNR < 20 {
+		t.Errorf("Invalid similarity: %#v", result)
+	}
+}
@@ -0,0 +1,84 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+// BlendMode defines how a layer is mixed with the image below it.
+// Other values are rejected
+type BlendMode int
+
+const (
+	BLEND_OVER BlendMode = iota
+	BLEND_MULTIPLY
+	BLEND_SCREEN
+	BLEND_OVERLAY
+	BLEND_DARKEN
+	BLEND_LIGHTEN
+	BLEND_COLOUR_DODGE
+	BLEND_COLOUR_BURN
+	BLEND_HARD_LIGHT
+	BLEND_SOFT_LIGHT
+	BLEND_DIFFERENCE
+	BLEND_EXCLUSION
+	BLEND_ADD
+	BLEND_SATURATE
+)
+
+// Layer is an image composited over the processed image.
+// Like WatermarkImage, it's placed by Gravity and moved inwards from the
+// gravity edges by the Left and Top offsets: use NORTHWEST to place it
+// at the Left and Top coordinates.
+type Layer struct {
+	Buf     []byte
+	Gravity Gravity
+	Left    int
+	Top     int
+	Mode    BlendMode
+	// Opacity from 0 to 1, opaque if zero
+	Opacity float32
+}
+
+// Composite stacks the layers over the base image, in order,
+// each one blended with the result of the previous ones.
+// The output has the size and type of the base image.
+// Requires libvips 8.6+
+func Composite(base []byte, layers []Layer) ([]byte, error) {
+	return Resize(base, Options{Composite: layers})
+}
+
+// Composite the layers over the image
+func (i *Image) Composite(layers []Layer) ([]byte, error) {
+	options := Options{Composite: layers}
+	return i.Process(options)
+}
+
+func compositeImage(image *C.VipsImage, layers []Layer) (*C.VipsImage, error) {
+	for _, layer := range layers {
+		sub, _, err := vipsRead(layer.Buf)
+		if err != nil {
+			C.g_object_unref(C.gpointer(image))
+			return nil, err
+		}
+
+		opacity := float64(layer.Opacity)
+		if opacity <= 0 || opacity > 1 {
+			opacity = 1
+		}
+
+		left, top := calculatePosition(int(image.Xsize), int(image.Ysize),
+			int(sub.Xsize), int(sub.Ysize), layer.Gravity, layer.Left, layer.Top)
+
+		debug("Composite layer: %dx%d at %d,%d", sub.Xsize, sub.Ysize, left, top)
+
+		image, err = vipsComposite(image, sub, layer.Mode, left, top, opacity)
+		C.g_object_unref(C.gpointer(sub))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return image, nil
+}
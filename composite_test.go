@@ -0,0 +1,58 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestComposite(t *testing.T) {
+	layers := []Layer{
+		// Greyscale layer over a colour image
+		{Buf: documentImage(200, 100), Mode: BLEND_MULTIPLY},
+		{Buf: readImage("transparent.png"), Gravity: NORTHWEST, Left: 10, Top: 10, Opacity: 0.5},
+		{Buf: readImage("test.jpg"), Gravity: SOUTHEAST, Mode: BLEND_SCREEN},
+	}
+
+	buf, err := Composite(readImage("test.png"), layers)
+	if err != nil {
+		t.Fatalf("Cannot composite the layers: %s", err)
+	}
+	if err := assertSize(buf, 400, 300); err != nil {
+		t.Error(err)
+	}
+	if DetermineImageType(buf) != PNG {
+		t.Fatal("Image is not png")
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha {
+		t.Error("Composited image must keep the alpha channel")
+	}
+
+	Write("fixtures/test_composite_out.png", buf)
+}
+
+func TestImageComposite(t *testing.T) {
+	image := initImage("test.jpg")
+	buf, err := image.Composite([]Layer{{Buf: readImage("test.png"), Mode: BLEND_DIFFERENCE}})
+	if err != nil {
+		t.Fatalf("Cannot composite the layers: %s", err)
+	}
+	if err := assertSize(buf, 1680, 1050); err != nil {
+		t.Error(err)
+	}
+	if DetermineImageType(buf) != JPEG {
+		t.Fatal("Image is not jpeg")
+	}
+
+	Write("fixtures/test_composite_out.jpg", buf)
+}
+
+func TestCompositeInvalidMode(t *testing.T) {
+	_, err := Composite(readImage("test.png"), []Layer{{Buf: readImage("transparent.png"), Mode: BlendMode(99)}})
+	if err == nil {
+		t.Error("Unknown blend modes must fail")
+	}
+}
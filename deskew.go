@@ -0,0 +1,188 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"math"
+)
+
+const (
+	// Largest side of the greyscale thumbnail analysed to detect the skew
+	deskewSampleSize = 800
+	// Largest skew angle detected, in degrees
+	deskewMaxAngle = 15
+)
+
+// DeskewOptions defines how the skew is corrected by Deskew
+type DeskewOptions struct {
+	// Minimum absolute skew angle in degrees to correct.
+	// Smaller skews are ignored and the image is returned untouched
+	MinAngle float64
+	// Colour of the areas uncovered by the rotation.
+	// White if nil, like the paper of scanned documents
+	Background *Color
+	// Keep the image size instead of expanding the canvas
+	Crop bool
+}
+
+// DetectSkew estimates the clockwise angle in degrees, within ±15°,
+// of the dominant text lines of a document image. The projection profile
+// of the dark pixels of a greyscale thumbnail is computed for every
+// candidate angle, keeping the sharpest one. Uniform images, such as
+// blank pages, have no skew
+func DetectSkew(buf []byte) (float64, error) {
+	defer C.vips_thread_shutdown()
+
+	width, height, err := placeholderSize(buf, deskewSampleSize)
+	if err != nil {
+		return 0, err
+	}
+
+	image, err := readScaledImage(buf, width, height)
+	if err != nil {
+		return 0, err
+	}
+
+	image, err = vipsLuminance(image)
+	if err != nil {
+		return 0, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	pixels, err := vipsImagePixels(image)
+	if err != nil {
+		return 0, err
+	}
+
+	return detectSkewAngle(pixels, int(image.Xsize), int(image.Ysize))
+}
+
+// Deskew detects the skew of a document image and rotates it by the
+// opposite angle. The detected angle is returned along with the image
+func Deskew(buf []byte, o DeskewOptions) ([]byte, float64, error) {
+	angle, err := DetectSkew(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if angle == 0 || math.Abs(angle) < o.MinAngle {
+		return buf, angle, nil
+	}
+
+	rotation := Rotation{Angle: -angle, Background: backgroundColor(o.Background), Crop: o.Crop}
+	out, err := Resize(buf, Options{Rotation: rotation})
+	if err != nil {
+		return nil, angle, err
+	}
+
+	return out, angle, nil
+}
+
+// Detect the skew angle of the document image
+func (i *Image) DetectSkew() (float64, error) {
+	return DetectSkew(i.buffer)
+}
+
+// Straighten the document image, returning the detected skew angle
+func (i *Image) Deskew(o DeskewOptions) ([]byte, float64, error) {
+	buf, angle, err := Deskew(i.buffer, o)
+	if err != nil {
+		return nil, angle, err
+	}
+	i.buffer = buf
+	return buf, angle, nil
+}
+
+func detectSkewAngle(pixels []byte, width, height int) (float64, error) {
+	threshold := otsuThreshold(pixels)
+
+	var xs, ys []float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if int(pixels[y*width+x]) < threshold {
+				xs = append(xs, float64(x))
+				ys = append(ys, float64(y))
+			}
+		}
+	}
+
+	// Nothing but dark or light pixels, such as blank pages:
+	// there's no line to straighten
+	if len(xs) == 0 || len(xs) == len(pixels) {
+		return 0, nil
+	}
+
+	diagonal := math.Hypot(float64(width), float64(height))
+	bins := make([]float64, int(2*diagonal)+2)
+
+	// The profile along the skew angle has the sharpest peaks (text lines)
+	// and valleys (line spacing), maximising the sum of squared bin counts
+	score := func(angle float64) float64 {
+		for i := range bins {
+			bins[i] = 0
+		}
+		sin, cos := math.Sincos(angle * math.Pi / 180)
+		for i := range xs {
+			bins[int(ys[i]*cos-xs[i]*sin+diagonal)]++
+		}
+		sum := 0.0
+		for _, count := range bins {
+			sum += count * count
+		}
+		return sum
+	}
+
+	search := func(from, to, step float64) float64 {
+		best, bestScore := 0.0, -1.0
+		for angle := from; angle <= to+step/2; angle += step {
+			if s := score(angle); s > bestScore {
+				best, bestScore = angle, s
+			}
+		}
+		return best
+	}
+
+	coarse := search(-deskewMaxAngle, deskewMaxAngle, 0.5)
+	fine := search(coarse-0.5, coarse+0.5, 0.05)
+
+	// Round to the search precision
+	return math.Floor(fine*100+0.5) / 100, nil
+}
+
+// otsuThreshold returns the grey level which best separates
+// the dark and light pixels, maximising the between-class variance
+func otsuThreshold(pixels []byte) int {
+	var histogram [256]float64
+	for _, p := range pixels {
+		histogram[p]++
+	}
+
+	total := float64(len(pixels))
+	sum := 0.0
+	for level, count := range histogram {
+		sum += float64(level) * count
+	}
+
+	threshold, best := 0, -1.0
+	weight, darkSum := 0.0, 0.0
+	for level, count := range histogram {
+		weight += count
+		if weight == 0 || weight == total {
+			continue
+		}
+		darkSum += float64(level) * count
+
+		darkMean := darkSum / weight
+		lightMean := (sum - darkSum) / (total - weight)
+		variance := weight * (total - weight) * (darkMean - lightMean) * (darkMean - lightMean)
+		if variance > best {
+			threshold, best = level+1, variance
+		}
+	}
+
+	return threshold
+}
@@ -0,0 +1,137 @@
+package bimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// documentImage draws black text-like lines over a white page
+func documentImage(width, height int) []byte {
+	page := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := uint8(255)
+			if x > width/10 && x < width*9/10 && y%20 < 6 && (x/15)%4 != 3 {
+				c = 0
+			}
+			page.SetGray(x, y, color.Gray{c})
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, page)
+	return buf.Bytes()
+}
+
+func TestDetectSkewAngle(t *testing.T) {
+	width, height := 400, 300
+	for _, skew := range []float64{-7, 0, 3} {
+		tan := math.Tan(skew * math.Pi / 180)
+		pixels := make([]byte, width*height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				pixels[y*width+x] = 255
+				// Lines going down to the right for a clockwise skew
+				line := float64(y) - float64(x)*tan
+				if math.Mod(line+1000, 20) < 4 {
+					pixels[y*width+x] = 0
+				}
+			}
+		}
+
+		angle, err := detectSkewAngle(pixels, width, height)
+		if err != nil {
+			t.Fatalf("Cannot detect the skew: %s", err)
+		}
+		if math.Abs(angle-skew) > 0.2 {
+			t.Errorf("Invalid skew angle: %f != %f", angle, skew)
+		}
+	}
+
+	angle, err := detectSkewAngle(make([]byte, width*height), width, height)
+	if err != nil || angle != 0 {
+		t.Errorf("Uniform images have no skew: %f, %v", angle, err)
+	}
+}
+
+func TestDeskew(t *testing.T) {
+	skewed, err := NewImage(documentImage(600, 400)).RotateBy(Rotation{Angle: 4, Background: Color{255, 255, 255}})
+	if err != nil {
+		t.Fatalf("Cannot rotate the image: %s", err)
+	}
+
+	document := NewImage(skewed)
+	buf, angle, err := document.Deskew(DeskewOptions{MinAngle: 0.5})
+	if err != nil {
+		t.Fatalf("Cannot deskew the image: %s", err)
+	}
+	if math.Abs(angle-4) > 0.3 {
+		t.Errorf("Invalid skew angle: %f", angle)
+	}
+
+	straight, err := DetectSkew(buf)
+	if err != nil {
+		t.Fatalf("Cannot detect the skew: %s", err)
+	}
+	if math.Abs(straight) > 0.3 {
+		t.Errorf("Image is still skewed: %f", straight)
+	}
+
+	Write("fixtures/test_deskew_out.png", buf)
+}
+
+func TestDeskewBackground(t *testing.T) {
+	skewed, err := NewImage(documentImage(600, 400)).RotateBy(Rotation{Angle: 4, Background: Color{255, 255, 255}})
+	if err != nil {
+		t.Fatalf("Cannot rotate the image: %s", err)
+	}
+
+	buf, _, err := Deskew(skewed, DeskewOptions{Background: &Color{}})
+	if err != nil {
+		t.Fatalf("Cannot deskew the image: %s", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("Cannot decode the image: %s", err)
+	}
+
+	// Corners are uncovered by the rotation
+	if corner := color.GrayModel.Convert(img.At(0, 0)).(color.Gray); corner.Y > 10 {
+		t.Errorf("Uncovered areas must be black: %d", corner.Y)
+	}
+}
+
+func TestDeskewMinAngle(t *testing.T) {
+	buf := documentImage(600, 400)
+
+	out, angle, err := Deskew(buf, DeskewOptions{MinAngle: 1})
+	if err != nil {
+		t.Fatalf("Cannot deskew the image: %s", err)
+	}
+	if math.Abs(angle) >= 1 || !bytes.Equal(out, buf) {
+		t.Errorf("Straight image must be kept untouched: %f", angle)
+	}
+}
+
+func TestDeskewBlankPage(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 600, 400))
+	for i := range blank.Pix {
+		blank.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, blank)
+	page := buf.Bytes()
+
+	out, angle, err := Deskew(page, DeskewOptions{MinAngle: 0.5})
+	if err != nil {
+		t.Fatalf("Cannot deskew a blank page: %s", err)
+	}
+	if angle != 0 || !bytes.Equal(out, page) {
+		t.Errorf("Blank page must be kept untouched: %f", angle)
+	}
+}
@@ -0,0 +1,334 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// HashKind defines the perceptual hashing algorithm
+type HashKind int
+
+const (
+	// Average hash: pixels brighter than the mean of an 8x8 greyscale thumbnail
+	AHASH HashKind = iota
+	// Difference hash: horizontal gradients of a 9x8 greyscale thumbnail
+	DHASH
+	// DCT hash: low frequencies of a 32x32 greyscale thumbnail above their median
+	PHASH
+)
+
+var hashSizes = map[HashKind][2]int{
+	AHASH: {8, 8},
+	DHASH: {9, 8},
+	PHASH: {32, 32},
+}
+
+// PerceptualHash computes a 64 bits perceptual hash of the image.
+// libvips shrinks the image and extracts its luminance, then the hash
+// bits are calculated from the resulting thumbnail pixels.
+// Similar images have hashes with a small Hamming distance
+func PerceptualHash(buf []byte, kind HashKind) (uint64, error) {
+	defer C.vips_thread_shutdown()
+
+	size, ok := hashSizes[kind]
+	if !ok {
+		return 0, errors.New("Unsupported perceptual hash kind")
+	}
+
+	image, err := readScaledImage(buf, size[0], size[1])
+	if err != nil {
+		return 0, err
+	}
+
+	image, err = vipsLuminance(image)
+	if err != nil {
+		return 0, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	if int(image.Xsize) != size[0] || int(image.Ysize) != size[1] {
+		return 0, errors.New("Cannot shrink the image to the hash size")
+	}
+
+	pixels, err := vipsImagePixels(image)
+	if err != nil {
+		return 0, err
+	}
+
+	switch kind {
+	case DHASH:
+		return differenceHash(pixels, size[0], size[1]), nil
+	case PHASH:
+		return dctHash(pixels, size[0]), nil
+	}
+	return averageHash(pixels), nil
+}
+
+// Get the perceptual hash of the image
+func (i *Image) PerceptualHash(kind HashKind) (uint64, error) {
+	return PerceptualHash(i.buffer, kind)
+}
+
+// HammingDistance returns the number of different bits between two hashes
+func HammingDistance(a, b uint64) int {
+	distance := 0
+	for x := a ^ b; x != 0; x &= x - 1 {
+		distance++
+	}
+	return distance
+}
+
+func averageHash(pixels []byte) uint64 {
+	sum := 0
+	for _, p := range pixels {
+		sum += int(p)
+	}
+	mean := float64(sum) / float64(len(pixels))
+
+	var hash uint64
+	for index, p := range pixels {
+		if float64(p) > mean {
+			hash |= 1 << uint(index)
+		}
+	}
+	return hash
+}
+
+func differenceHash(pixels []byte, width, height int) uint64 {
+	var hash uint64
+	bit := uint(0)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			if pixels[y*width+x] > pixels[y*width+x+1] {
+				hash |= 1 << bit
+			}
+			bit++
+		}
+	}
+	return hash
+}
+
+func dctHash(pixels []byte, size int) uint64 {
+	// Separable 2D DCT-II, only the 8x8 low frequencies are needed
+	const low = 8
+
+	rows := make([]float64, size*low)
+	for y := 0; y < size; y++ {
+		for u := 0; u < low; u++ {
+			sum := 0.0
+			for x := 0; x < size; x++ {
+				sum += float64(pixels[y*size+x]) * math.Cos(math.Pi*float64(u)*(2*float64(x)+1)/float64(2*size))
+			}
+			rows[y*low+u] = sum
+		}
+	}
+
+	coefficients := make([]float64, low*low)
+	for v := 0; v < low; v++ {
+		for u := 0; u < low; u++ {
+			sum := 0.0
+			for y := 0; y < size; y++ {
+				sum += rows[y*low+u] * math.Cos(math.Pi*float64(v)*(2*float64(y)+1)/float64(2*size))
+			}
+			coefficients[v*low+u] = sum
+		}
+	}
+
+	// The DC coefficient is excluded from the median as it only reflects the mean brightness
+	sorted := make([]float64, len(coefficients)-1)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for index, c := range coefficients {
+		if c > median {
+			hash |= 1 << uint(index)
+		}
+	}
+	return hash
+}
+
+// HashMatch is a hash found in a HashIndex
+type HashMatch struct {
+	ID       string
+	Hash     uint64
+	Distance int
+}
+
+type hashNode struct {
+	id       string
+	hash     uint64
+	children map[int]*hashNode
+}
+
+// HashIndex is an in-memory BK-tree of perceptual hashes,
+// allowing to find every hash within a given Hamming distance.
+// It's safe for concurrent use by multiple goroutines.
+type HashIndex struct {
+	mutex sync.RWMutex
+	root  *hashNode
+	size  int
+}
+
+// Creates a new empty hash index
+func NewHashIndex() *HashIndex {
+	return &HashIndex{}
+}
+
+// Add a hash identified by the given ID to the index
+func (x *HashIndex) Add(id string, hash uint64) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.size++
+	node := &hashNode{id: id, hash: hash}
+	if x.root == nil {
+		x.root = node
+		return
+	}
+
+	for current := x.root; ; {
+		distance := HammingDistance(current.hash, hash)
+		child, ok := current.children[distance]
+		if !ok {
+			if current.children == nil {
+				current.children = make(map[int]*hashNode)
+			}
+			current.children[distance] = node
+			return
+		}
+		current = child
+	}
+}
+
+// Find every hash within the given Hamming distance, nearest first
+func (x *HashIndex) Find(hash uint64, distance int) []HashMatch {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	var matches []HashMatch
+	if x.root == nil {
+		return matches
+	}
+
+	queue := []*hashNode{x.root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		d := HammingDistance(node.hash, hash)
+		if d <= distance {
+			matches = append(matches, HashMatch{ID: node.id, Hash: node.hash, Distance: d})
+		}
+
+		// Triangle inequality: only children within [d - distance, d + distance] may match
+		for childDistance, child := range node.children {
+			if childDistance >= d-distance && childDistance <= d+distance {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	sort.Sort(hashMatches(matches))
+	return matches
+}
+
+// Len returns the number of hashes in the index
+func (x *HashIndex) Len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return x.size
+}
+
+var hashIndexMagic = []byte("BKH1")
+
+// MarshalBinary serialises the index, so it can be persisted
+func (x *HashIndex) MarshalBinary() ([]byte, error) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	var buf bytes.Buffer
+	buf.Write(hashIndexMagic)
+	binary.Write(&buf, binary.BigEndian, uint64(x.size))
+
+	// Parents are written before their children, so the tree can be rebuilt by insertion
+	scratch := make([]byte, binary.MaxVarintLen64)
+	queue := []*hashNode{}
+	if x.root != nil {
+		queue = append(queue, x.root)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		binary.Write(&buf, binary.BigEndian, node.hash)
+		buf.Write(scratch[:binary.PutUvarint(scratch, uint64(len(node.id)))])
+		buf.WriteString(node.id)
+
+		for _, child := range node.children {
+			queue = append(queue, child)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the index content with the serialised index
+func (x *HashIndex) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	magic := make([]byte, len(hashIndexMagic))
+	if _, err := reader.Read(magic); err != nil || !bytes.Equal(magic, hashIndexMagic) {
+		return errors.New("Invalid hash index data")
+	}
+
+	var size uint64
+	if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+		return errors.New("Invalid hash index data")
+	}
+
+	index := NewHashIndex()
+	for n := uint64(0); n < size; n++ {
+		var hash uint64
+		if err := binary.Read(reader, binary.BigEndian, &hash); err != nil {
+			return errors.New("Invalid hash index data")
+		}
+
+		length, err := binary.ReadUvarint(reader)
+		if err != nil || length > uint64(reader.Len()) {
+			return errors.New("Invalid hash index data")
+		}
+
+		id := make([]byte, length)
+		reader.Read(id)
+		index.Add(string(id), hash)
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.root, x.size = index.root, index.size
+
+	return nil
+}
+
+type hashMatches []HashMatch
+
+func (m hashMatches) Len() int      { return len(m) }
+func (m hashMatches) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m hashMatches) Less(i, j int) bool {
+	if m[i].Distance != m[j].Distance {
+		return m[i].Distance < m[j].Distance
+	}
+	return m[i].ID < m[j].ID
+}
@@ -0,0 +1,108 @@
+package bimg
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPerceptualHash(t *testing.T) {
+	buf := readImage("test.jpg")
+	resized, err := Resize(buf, Options{Width: 400, Quality: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kind := range []HashKind{AHASH, DHASH, PHASH} {
+		hash, err := PerceptualHash(buf, kind)
+		if err != nil {
+			t.Fatalf("Cannot hash the image: %s", err)
+		}
+
+		other, err := NewImage(resized).PerceptualHash(kind)
+		if err != nil {
+			t.Fatalf("Cannot hash the image: %s", err)
+		}
+
+		if hash == 0 || HammingDistance(hash, other) > 10 {
+			t.Errorf("Invalid hash distance for kind %d: %016x, %016x", kind, hash, other)
+		}
+	}
+}
+
+func TestPerceptualHashUnsupportedKind(t *testing.T) {
+	_, err := PerceptualHash(readImage("test.jpg"), HashKind(10))
+	if err == nil {
+		t.Fatal("Expected unsupported kind error")
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b     uint64
+		distance int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xFF, 0x0F, 4},
+		{0, 0xFFFFFFFFFFFFFFFF, 64},
+	}
+
+	for _, test := range tests {
+		if d := HammingDistance(test.a, test.b); d != test.distance {
+			t.Errorf("Invalid distance for %x, %x: %d", test.a, test.b, d)
+		}
+	}
+}
+
+func TestHashIndex(t *testing.T) {
+	index := NewHashIndex()
+	for n := 0; n < 64; n++ {
+		index.Add(fmt.Sprintf("image-%d", n), uint64(1)<<uint(n)-1)
+	}
+
+	if index.Len() != 64 {
+		t.Fatalf("Invalid index length: %d", index.Len())
+	}
+
+	matches := index.Find(uint64(1)<<10-1, 2)
+	if len(matches) != 5 {
+		t.Fatalf("Invalid number of matches: %#v", matches)
+	}
+	if matches[0].ID != "image-10" || matches[0].Distance != 0 {
+		t.Errorf("Invalid nearest match: %#v", matches[0])
+	}
+	for _, match := range matches {
+		if match.Distance > 2 || HammingDistance(match.Hash, uint64(1)<<10-1) != match.Distance {
+			t.Errorf("Invalid match: %#v", match)
+		}
+	}
+}
+
+func TestHashIndexSerialization(t *testing.T) {
+	index := NewHashIndex()
+	index.Add("a", 0x00FF)
+	index.Add("b", 0x01FF)
+	index.Add("c", 0xFF00)
+
+	data, err := index.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewHashIndex()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Cannot restore the index: %s", err)
+	}
+
+	if restored.Len() != 3 {
+		t.Fatalf("Invalid index length: %d", restored.Len())
+	}
+	matches := restored.Find(0x00FF, 1)
+	if len(matches) != 2 || matches[0].ID != "a" || matches[1].ID != "b" {
+		t.Fatalf("Invalid matches: %#v", matches)
+	}
+
+	if err := restored.UnmarshalBinary([]byte("invalid")); err == nil {
+		t.Fatal("Expected invalid data error")
+	}
+}
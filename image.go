@@ -1,5 +1,9 @@
 package bimg
 
+// Image provides a fluent interface over an image buffer.
+// Every transformation replaces the image buffer with its output,
+// so an Image must not be used concurrently by multiple goroutines.
+// Use Clone() to keep a copy or ImmutableImage for a read-only variant.
 type Image struct {
 	buffer []byte
 }
@@ -113,6 +117,12 @@ func (i *Image) Watermark(w Watermark) ([]byte, error) {
 	return i.Process(options)
 }
 
+// Add an image, such as a logo, as watermark on the given image
+func (i *Image) WatermarkImage(w WatermarkImage) ([]byte, error) {
+	options := Options{WatermarkImage: w}
+	return i.Process(options)
+}
+
 // Zoom the image by the given factor.
 // You should probably call Extract() before
 func (i *Image) Zoom(factor int) ([]byte, error) {
@@ -126,6 +136,12 @@ func (i *Image) Rotate(a Angle) ([]byte, error) {
 	return i.Process(options)
 }
 
+// Rotate the image clockwise by an arbitrary angle in degrees
+func (i *Image) RotateBy(r Rotation) ([]byte, error) {
+	options := Options{Rotation: r}
+	return i.Process(options)
+}
+
 // Flip the image about the vertical Y axis
 func (i *Image) Flip() ([]byte, error) {
 	options := Options{Flip: true}
@@ -150,6 +166,85 @@ func (i *Image) Colourspace(c Interpretation) ([]byte, error) {
 	return i.Process(options)
 }
 
+// Adjust the image colours (brightness, contrast, gamma, saturation and hue)
+func (i *Image) Adjust(a Adjust) ([]byte, error) {
+	options := Options{Adjust: a}
+	return i.Process(options)
+}
+
+// Adjust the image brightness by the given relative amount, from -1 to 1
+func (i *Image) Brightness(amount float64) ([]byte, error) {
+	return i.Adjust(Adjust{Brightness: amount})
+}
+
+// Adjust the image contrast by the given relative amount, from -1 to 1
+func (i *Image) Contrast(amount float64) ([]byte, error) {
+	return i.Adjust(Adjust{Contrast: amount})
+}
+
+// Apply a gamma correction, values above 1 lighten the mid-tones
+func (i *Image) Gamma(gamma float64) ([]byte, error) {
+	return i.Adjust(Adjust{Gamma: gamma})
+}
+
+// Adjust the image saturation by the given relative amount, from -1 to 1
+func (i *Image) Saturation(amount float64) ([]byte, error) {
+	return i.Adjust(Adjust{Saturation: amount})
+}
+
+// Rotate the image hue by the given angle in degrees
+func (i *Image) Hue(degrees float64) ([]byte, error) {
+	return i.Adjust(Adjust{Hue: degrees})
+}
+
+// Apply filter effects (greyscale, sepia, duotone, posterise, threshold, invert)
+func (i *Image) Effects(e Effects) ([]byte, error) {
+	options := Options{Effects: e}
+	return i.Process(options)
+}
+
+// Trim the uniform borders of the image
+func (i *Image) Trim(t Trim) ([]byte, error) {
+	t.Enabled = true
+	options := Options{Trim: t}
+	return i.Process(options)
+}
+
+// Get the image area remaining once its uniform borders are trimmed
+func (i *Image) FindTrim(t Trim) (Area, error) {
+	return FindTrim(i.buffer, t)
+}
+
+// Flatten the alpha channel onto the background colour.
+// Images are also flattened onto Options.Background, white if nil,
+// before being saved in types without alpha channel, such as JPEG
+func (i *Image) Flatten(background Color) ([]byte, error) {
+	options := Options{Flatten: true, Background: &background}
+	return i.Process(options)
+}
+
+// Add an opaque alpha channel to the image, if missing.
+// Images of types without alpha, such as JPEG, are converted to PNG
+func (i *Image) AddAlpha() ([]byte, error) {
+	options := Options{Alpha: ALPHA_ADD}
+	if !imageTypeHasAlpha(DetermineImageType(i.buffer)) {
+		options.Type = PNG
+	}
+	return i.Process(options)
+}
+
+// Remove the alpha channel of the image, without flattening it
+func (i *Image) RemoveAlpha() ([]byte, error) {
+	options := Options{Alpha: ALPHA_REMOVE}
+	return i.Process(options)
+}
+
+// Replace the image by its alpha channel, as a greyscale image
+func (i *Image) ExtractAlpha() ([]byte, error) {
+	options := Options{Alpha: ALPHA_EXTRACT, Interpretation: INTERPRETATION_B_W}
+	return i.Process(options)
+}
+
 // Transform the image by custom options
 func (i *Image) Process(o Options) ([]byte, error) {
 	image, err := Resize(i.buffer, o)
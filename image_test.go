@@ -193,6 +193,106 @@ func TestImageWatermarkNoReplicate(t *testing.T) {
 	Write("fixtures/test_watermark_replicate_out.jpg", buf)
 }
 
+// watermarkArea returns the area covered by a white text
+// watermarked over a black canvas
+func TestImageWatermarkCMYK(t *testing.T) {
+	cmyk, err := initImage("test.jpg").Process(Options{Width: 400, Interpretation: INTERPRETATION_CMYK})
+	if err != nil {
+		t.Fatalf("Cannot convert the image: %s", err)
+	}
+	before, err := AverageColor(cmyk)
+	if err != nil {
+		t.Fatalf("Cannot read the average colour: %s", err)
+	}
+
+	buf, err := NewImage(cmyk).Watermark(Watermark{
+		Text:    "Copy me if you can",
+		Opacity: 0.5,
+		Width:   200,
+		Color:   Color{255, 255, 255},
+	})
+	if err != nil {
+		t.Fatalf("Cannot watermark the image: %s", err)
+	}
+
+	// The image must not be darkened by the text colour
+	after, err := AverageColor(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the average colour: %s", err)
+	}
+	if ColorDistance(before, after) > 20 {
+		t.Errorf("Invalid watermarked colours: %v != %v", after, before)
+	}
+}
+
+func watermarkArea(t *testing.T, w Watermark) Area {
+	canvas, err := NewCanvas(400, 300, Color{}, 1)
+	if err != nil {
+		t.Fatalf("Cannot create the canvas: %s", err)
+	}
+
+	w.Text = "Copy me"
+	w.Opacity = 1
+	w.Color = Color{255, 255, 255}
+	w.Width = 150
+	buf, err := canvas.Watermark(w)
+	if err != nil {
+		t.Fatalf("Cannot watermark the image: %s", err)
+	}
+
+	area, err := FindTrim(buf, Trim{Background: Color{}})
+	if err != nil {
+		t.Fatalf("Cannot find the watermark area: %s", err)
+	}
+	return area
+}
+
+func TestImageWatermarkDefaultPosition(t *testing.T) {
+	// The single text starts at the legacy fixed position
+	area := watermarkArea(t, Watermark{NoReplicate: true})
+	if area.Left < 100 || area.Left > 110 || area.Top < 100 || area.Top > 120 {
+		t.Errorf("Invalid watermark position: %#v", area)
+	}
+
+	area = watermarkArea(t, Watermark{NoReplicate: true, Positioned: true, Gravity: SOUTHEAST, Left: 10, Top: 10})
+	if right, bottom := area.Left+area.Width, area.Top+area.Height; right > 390 || right < 370 || bottom > 290 || bottom < 260 {
+		t.Errorf("Invalid watermark position: %#v", area)
+	}
+}
+
+func TestImageWatermarkPlacement(t *testing.T) {
+	image := initImage("test.png")
+
+	buf, err := image.Watermark(Watermark{
+		Text:        "Copy me\nif you can",
+		Opacity:     0.8,
+		Width:       200,
+		DPI:         100,
+		NoReplicate: true,
+		Positioned:  true,
+		Gravity:     SOUTHEAST,
+		Left:        10,
+		Top:         10,
+		Color:       Color{255, 0, 0},
+		Angle:       -45,
+		Align:       ALIGN_CENTRE,
+	})
+	if err != nil {
+		t.Fatalf("Cannot watermark the image: %s", err)
+	}
+
+	err = assertSize(buf, 400, 300)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if DetermineImageType(buf) != PNG {
+		t.Fatal("Image is not png")
+	}
+
+	Write("fixtures/test_watermark_placement_out.png", buf)
+}
+
 func TestImageZoom(t *testing.T) {
 	image := initImage("test.jpg")
 
@@ -238,6 +338,37 @@ func TestImageRotate(t *testing.T) {
 	Write("fixtures/test_image_rotate_out.jpg", buf)
 }
 
+func TestImageRotateBy(t *testing.T) {
+	buf, err := initImage("test.jpg").RotateBy(Rotation{Angle: 45, Background: Color{255, 255, 255}})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	// The canvas is expanded to fit the rotated image
+	size, err := Size(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image size: %s", err)
+	}
+	if size.Width < 1925 || size.Width > 1935 || size.Height != size.Width {
+		t.Errorf("Invalid rotated image size: %dx%d", size.Width, size.Height)
+	}
+	Write("fixtures/test_rotate_by_out.jpg", buf)
+
+	buf, err = initImage("test.png").RotateBy(Rotation{Angle: -10.5, Transparent: true, Crop: true})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha || metadata.Size.Width != 400 || metadata.Size.Height != 300 {
+		t.Errorf("Invalid image metadata: %#v", metadata)
+	}
+	Write("fixtures/test_rotate_by_out.png", buf)
+}
+
 func TestImageConvert(t *testing.T) {
 	buf, err := initImage("test.jpg").Convert(PNG)
 	if err != nil {
@@ -294,6 +425,159 @@ func TestImageColourspace(t *testing.T) {
 	}
 }
 
+func TestImageAdjust(t *testing.T) {
+	original, err := Stats(readImage("test.jpg"))
+	if err != nil {
+		t.Fatalf("Cannot get the image statistics: %s", err)
+	}
+
+	buf, err := initImage("test.jpg").Brightness(0.3)
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	brighter, err := Stats(buf)
+	if err != nil {
+		t.Fatalf("Cannot get the image statistics: %s", err)
+	}
+	if brighter.Bands[0].Mean <= original.Bands[0].Mean {
+		t.Errorf("Image is not brighter: %f <= %f", brighter.Bands[0].Mean, original.Bands[0].Mean)
+	}
+
+	buf, err = initImage("test.png").Adjust(Adjust{Contrast: 0.2, Gamma: 1.2, Saturation: -1, Hue: 90})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha || metadata.Size.Width != 400 || metadata.Size.Height != 300 {
+		t.Errorf("Invalid image metadata: %#v", metadata)
+	}
+
+	Write("fixtures/test_adjust_out.png", buf)
+}
+
+func TestImageAlpha(t *testing.T) {
+	tests := []struct {
+		name   string
+		method func(image *Image) ([]byte, error)
+		file   string
+		alpha  bool
+		bands  int
+	}{
+		{"flatten", func(image *Image) ([]byte, error) { return image.Flatten(Color{255, 0, 0}) }, "test.png", false, 3},
+		{"add", (*Image).AddAlpha, "test.jpg", true, 4},
+		{"remove", (*Image).RemoveAlpha, "test.png", false, 3},
+		{"extract", (*Image).ExtractAlpha, "test.png", false, 1},
+	}
+
+	for _, test := range tests {
+		buf, err := test.method(initImage(test.file))
+		if err != nil {
+			t.Fatalf("Cannot %s the alpha channel: %s", test.name, err)
+		}
+
+		metadata, err := Metadata(buf)
+		if err != nil {
+			t.Fatalf("Cannot read the image metadata: %s", err)
+		}
+		if metadata.Alpha != test.alpha || metadata.Channels != test.bands {
+			t.Errorf("Invalid %s alpha channel: %t, %d bands", test.name, metadata.Alpha, metadata.Channels)
+		}
+
+		Write("fixtures/test_alpha_"+test.name+"_out.png", buf)
+	}
+}
+
+func TestFlattenBeforeSave(t *testing.T) {
+	buf, err := Resize(readImage("transparent.png"), Options{Type: JPEG, Background: &Color{0, 0, 255}})
+	if err != nil {
+		t.Fatalf("Cannot convert the image: %s", err)
+	}
+
+	stats, err := Stats(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image stats: %s", err)
+	}
+	// The transparent areas become blue
+	if stats.Bands[2].Max < 250 {
+		t.Errorf("Image was not flattened onto the background: %#v", stats.Bands[2])
+	}
+
+	Write("fixtures/test_flatten_out.jpg", buf)
+}
+
+func TestImageFlattenBlack(t *testing.T) {
+	buf, err := initImage("transparent.png").Flatten(Color{})
+	if err != nil {
+		t.Fatalf("Cannot flatten the image: %s", err)
+	}
+
+	stats, err := Stats(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image stats: %s", err)
+	}
+	if len(stats.Bands) != 3 {
+		t.Fatalf("Image was not flattened: %d bands", len(stats.Bands))
+	}
+	// The transparent areas become black
+	for _, band := range stats.Bands {
+		if band.Min > 5 {
+			t.Errorf("Image was not flattened onto black: %#v", band)
+		}
+	}
+}
+
+func TestImageEffects(t *testing.T) {
+	buf, err := initImage("test.png").Effects(Effects{Greyscale: true})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha {
+		t.Error("Alpha channel must be preserved")
+	}
+	if grey, _ := IsGreyscale(buf); !grey {
+		t.Error("Image must be greyscale")
+	}
+
+	buf, err = initImage("test.jpg").Effects(Effects{
+		Sepia:     true,
+		Duotone:   Duotone{Shadow: Color{20, 0, 80}, Highlight: Color{255, 220, 120}},
+		Posterise: 4,
+		Invert:    true,
+	})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+	if err := assertSize(buf, 1680, 1050); err != nil {
+		t.Error(err)
+	}
+	Write("fixtures/test_effects_out.jpg", buf)
+
+	buf, err = initImage("test.jpg").Process(Options{Type: PNG, Effects: Effects{Threshold: 128}})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	histogram, err := Histogram(buf)
+	if err != nil {
+		t.Fatalf("Cannot get the image histogram: %s", err)
+	}
+	for value, count := range histogram.Bands[0] {
+		if count > 0 && value != 0 && value != 255 {
+			t.Fatalf("Thresholded image has intermediate values: %d", value)
+		}
+	}
+}
+
 func TestImageColourspaceIsSupported(t *testing.T) {
 	supported, err := initImage("test.jpg").ColourspaceIsSupported()
 	if err != nil {
@@ -0,0 +1,91 @@
+package bimg
+
+// ImmutableImage is an image whose buffer is never modified once created.
+// Every transformation, done through Process with the same Options as
+// Image and Resize, returns a new *ImmutableImage, leaving the source
+// untouched, so several derivatives can be generated from the same image.
+//
+// Its methods may be called from several goroutines at once: the buffer is
+// copied on creation and only read afterwards, and each call decodes its own
+// libvips image. Only the buffer is covered by this guarantee. The messages
+// of libvips errors raised at the same time by different goroutines may be
+// mixed up, as libvips stores them in a single global buffer.
+type ImmutableImage struct {
+	buffer []byte
+}
+
+// Creates a new immutable image. The given buffer is copied,
+// so the caller may safely reuse it afterwards
+func NewImmutableImage(buf []byte) *ImmutableImage {
+	return &ImmutableImage{copyBuffer(buf)}
+}
+
+// Creates an immutable copy of the current image
+func (i *Image) Immutable() *ImmutableImage {
+	return NewImmutableImage(i.buffer)
+}
+
+// Creates a deep copy of the current image
+func (i *Image) Clone() *Image {
+	return NewImage(copyBuffer(i.buffer))
+}
+
+// Creates a deep copy of the current image
+func (i *ImmutableImage) Clone() *ImmutableImage {
+	return NewImmutableImage(i.buffer)
+}
+
+// Transform the image by custom options, returning a new image
+func (i *ImmutableImage) Process(o Options) (*ImmutableImage, error) {
+	buf, err := Resize(i.buffer, o)
+	if err != nil {
+		return nil, err
+	}
+	return &ImmutableImage{buf}, nil
+}
+
+// Creates a new pipeline of operations for the current image.
+// The image itself is not modified once the pipeline is executed
+func (i *ImmutableImage) Pipeline() *Pipeline {
+	return NewPipeline(i.buffer)
+}
+
+// Get image metadata (size, alpha channel, profile, EXIF rotation)
+func (i *ImmutableImage) Metadata() (ImageMetadata, error) {
+	return Metadata(i.buffer)
+}
+
+// Get the image interpretation type
+// See: http://www.vips.ecs.soton.ac.uk/supported/current/doc/html/libvips/VipsImage.html#VipsInterpretation
+func (i *ImmutableImage) Interpretation() (Interpretation, error) {
+	return ImageInterpretation(i.buffer)
+}
+
+// Check if the current image has a valid colourspace
+func (i *ImmutableImage) ColourspaceIsSupported() (bool, error) {
+	return ColourspaceIsSupported(i.buffer)
+}
+
+// Get image type format (jpeg, png, webp, tiff)
+func (i *ImmutableImage) Type() string {
+	return DetermineImageTypeName(i.buffer)
+}
+
+// Get image size
+func (i *ImmutableImage) Size() (ImageSize, error) {
+	return Size(i.buffer)
+}
+
+// Get a copy of the image buffer
+func (i *ImmutableImage) Image() []byte {
+	return copyBuffer(i.buffer)
+}
+
+func copyBuffer(buf []byte) []byte {
+	if buf == nil {
+		return nil
+	}
+	out := make([]byte, len(buf))
+	copy(out, buf)
+	return out
+}
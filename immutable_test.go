@@ -0,0 +1,88 @@
+package bimg
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestImmutableImageDerivatives(t *testing.T) {
+	source := readImage("test.jpg")
+	image := NewImmutableImage(source)
+
+	small, err := image.Process(Options{Width: 300, Height: 240, Embed: true})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+	large, err := image.Process(Options{Width: 800, Height: 600, Crop: true, Gravity: NORTH})
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	if err := assertSize(small.Image(), 300, 240); err != nil {
+		t.Error(err)
+	}
+	if err := assertSize(large.Image(), 800, 600); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(image.Image(), source) {
+		t.Fatal("Source image was modified")
+	}
+}
+
+// Run with -race to check the shared buffer is only read
+func TestImmutableImageConcurrency(t *testing.T) {
+	source := readImage("test.jpg")
+	image := NewImmutableImage(source)
+	widths := []int{100, 200, 300, 400, 500, 600, 700, 800}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(widths))
+	for index, width := range widths {
+		wg.Add(1)
+		go func(index, width int) {
+			defer wg.Done()
+
+			out, err := image.Process(Options{Width: width, Crop: true})
+			if err != nil {
+				errs[index] = err
+				return
+			}
+			size, err := out.Size()
+			if err == nil && size.Width != width {
+				t.Errorf("Invalid width size: %d", size.Width)
+			}
+			if _, err := image.Metadata(); err != nil {
+				t.Errorf("Cannot read the metadata: %s", err)
+			}
+			errs[index] = err
+		}(index, width)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Cannot process the image: %s", err)
+		}
+	}
+	if !bytes.Equal(image.Image(), source) {
+		t.Fatal("Source image was modified")
+	}
+}
+
+func TestImageClone(t *testing.T) {
+	image := initImage("test.jpg")
+	clone := image.Clone()
+
+	_, err := clone.Convert(PNG)
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	if image.Type() != "jpeg" {
+		t.Fatal("Original image was modified")
+	}
+	if clone.Type() != "png" {
+		t.Fatal("Invalid cloned image type")
+	}
+}
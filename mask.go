@@ -0,0 +1,154 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"math"
+)
+
+// Samples per pixel side used to antialias the edges of shape masks
+const maskSupersampling = 4
+
+type MaskShape int
+
+const (
+	MASK_NONE MaskShape = iota
+	MASK_ROUNDED
+	MASK_ELLIPSE
+	MASK_CIRCLE
+)
+
+// Mask makes the areas of the image outside of a shape transparent,
+// such as rounded corners or a circle for avatars.
+// A greyscale image can be used instead of a shape as alpha mask.
+type Mask struct {
+	Shape MaskShape
+	// Corner radius in pixels of MASK_ROUNDED
+	Radius int
+	// Image used as alpha mask, stretched to the image size:
+	// its alpha channel if it's transparent, otherwise its luminance.
+	// The shape is ignored if defined
+	Buf []byte
+	// Colour the image is flattened onto when the output type, such as JPEG,
+	// has no alpha channel, white if nil
+	Background *Color
+}
+
+// Mask the image with a shape or a greyscale image
+func (i *Image) Mask(m Mask) ([]byte, error) {
+	options := Options{Mask: m}
+	return i.Process(options)
+}
+
+func maskImage(image *C.VipsImage, m Mask, outputType ImageType) (*C.VipsImage, error) {
+	if m.Shape == MASK_NONE && len(m.Buf) == 0 {
+		return image, nil
+	}
+
+	width, height := int(image.Xsize), int(image.Ysize)
+
+	var mask *C.VipsImage
+	var err error
+	if len(m.Buf) > 0 {
+		mask, err = readMaskImage(m.Buf, width, height)
+	} else {
+		mask, err = vipsImageFromPixels(shapeMask(width, height, m.Shape, m.Radius), width, height, 1)
+	}
+	if err != nil {
+		C.g_object_unref(C.gpointer(image))
+		return nil, err
+	}
+	defer C.g_object_unref(C.gpointer(mask))
+
+	return vipsMask(image, mask, !imageTypeHasAlpha(outputType), backgroundColor(m.Background))
+}
+
+func readMaskImage(buf []byte, width, height int) (*C.VipsImage, error) {
+	image, _, err := vipsRead(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err = vipsAlphaMask(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(image.Xsize) == width && int(image.Ysize) == height {
+		return image, nil
+	}
+
+	return vipsAffine(image, float64(width)/float64(image.Xsize), float64(height)/float64(image.Ysize), BICUBIC)
+}
+
+// shapeMask renders the shape as a one band mask. Pixels crossed
+// by the shape edge are supersampled to compute their coverage
+func shapeMask(width, height int, shape MaskShape, radius int) []byte {
+	contains := shapeContains(float64(width), float64(height), shape, float64(radius))
+	pixels := make([]byte, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x), float64(y)
+
+			// The shapes are convex: the pixel is fully covered
+			// or uncovered when all its corners are
+			corners := 0
+			for _, corner := range [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				if contains(fx+corner[0], fy+corner[1]) {
+					corners++
+				}
+			}
+
+			switch corners {
+			case 4:
+				pixels[y*width+x] = 255
+			case 0:
+				pixels[y*width+x] = 0
+			default:
+				covered := 0
+				for sy := 0; sy < maskSupersampling; sy++ {
+					for sx := 0; sx < maskSupersampling; sx++ {
+						if contains(fx+(float64(sx)+0.5)/maskSupersampling, fy+(float64(sy)+0.5)/maskSupersampling) {
+							covered++
+						}
+					}
+				}
+				pixels[y*width+x] = uint8(covered * 255 / (maskSupersampling * maskSupersampling))
+			}
+		}
+	}
+
+	return pixels
+}
+
+func shapeContains(width, height float64, shape MaskShape, radius float64) func(x, y float64) bool {
+	switch shape {
+	case MASK_ELLIPSE:
+		return func(x, y float64) bool {
+			dx, dy := (x-width/2)/(width/2), (y-height/2)/(height/2)
+			return dx*dx+dy*dy <= 1
+		}
+	case MASK_CIRCLE:
+		radius = math.Min(width, height) / 2
+		return func(x, y float64) bool {
+			dx, dy := x-width/2, y-height/2
+			return dx*dx+dy*dy <= radius*radius
+		}
+	default:
+		radius = math.Max(0, math.Min(radius, math.Min(width, height)/2))
+		return func(x, y float64) bool {
+			if x < 0 || y < 0 || x > width || y > height {
+				return false
+			}
+			// Distance to the nearest corner circle centre
+			dx := x - math.Max(radius, math.Min(x, width-radius))
+			dy := y - math.Max(radius, math.Min(y, height-radius))
+			return dx*dx+dy*dy <= radius*radius
+		}
+	}
+}
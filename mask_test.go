@@ -0,0 +1,141 @@
+package bimg
+
+import (
+	"bytes"
+	"image/jpeg"
+	"testing"
+)
+
+func TestShapeMask(t *testing.T) {
+	tests := []struct {
+		shape   MaskShape
+		radius  int
+		covered []int
+		masked  []int
+	}{
+		{MASK_ROUNDED, 20, []int{50*100 + 50, 0*100 + 50, 50*100 + 0}, []int{0, 99, 99*100 + 99}},
+		{MASK_ROUNDED, 0, []int{0, 99, 99*100 + 99}, nil},
+		{MASK_ELLIPSE, 0, []int{50*100 + 50, 50*100 + 1}, []int{0, 99, 10*100 + 10}},
+		{MASK_CIRCLE, 0, []int{50*100 + 50}, []int{0, 99*100 + 99}},
+	}
+
+	for _, test := range tests {
+		pixels := shapeMask(100, 100, test.shape, test.radius)
+		for _, i := range test.covered {
+			if pixels[i] != 255 {
+				t.Errorf("Pixel %d of shape %d must be covered: %d", i, test.shape, pixels[i])
+			}
+		}
+		for _, i := range test.masked {
+			if pixels[i] != 0 {
+				t.Errorf("Pixel %d of shape %d must be masked: %d", i, test.shape, pixels[i])
+			}
+		}
+	}
+
+	// Antialiased edge
+	pixels := shapeMask(100, 100, MASK_CIRCLE, 0)
+	partial := 0
+	for _, p := range pixels {
+		if p > 0 && p < 255 {
+			partial++
+		}
+	}
+	if partial == 0 {
+		t.Error("Circle edges must be antialiased")
+	}
+}
+
+func TestMask(t *testing.T) {
+	buf, err := Resize(readImage("test.jpg"), Options{
+		Width:  300,
+		Height: 300,
+		Crop:   true,
+		Type:   PNG,
+		Mask:   Mask{Shape: MASK_CIRCLE},
+	})
+	if err != nil {
+		t.Fatalf("Cannot mask the image: %s", err)
+	}
+	if err := assertSize(buf, 300, 300); err != nil {
+		t.Error(err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha {
+		t.Error("Masked image must have an alpha channel")
+	}
+
+	Write("fixtures/test_mask_circle_out.png", buf)
+}
+
+func TestMaskFlatten(t *testing.T) {
+	image := initImage("test.png")
+	buf, err := image.Process(Options{
+		Type: JPEG,
+		Mask: Mask{Shape: MASK_ROUNDED, Radius: 40, Background: &Color{255, 255, 255}},
+	})
+	if err != nil {
+		t.Fatalf("Cannot mask the image: %s", err)
+	}
+	if err := assertSize(buf, 400, 300); err != nil {
+		t.Error(err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if metadata.Alpha || metadata.Type != "jpeg" {
+		t.Error("Masked image must be flattened")
+	}
+
+	Write("fixtures/test_mask_rounded_out.jpg", buf)
+}
+
+func TestMaskFlattenBackground(t *testing.T) {
+	tests := []struct {
+		background *Color
+		corner     Color
+	}{
+		{nil, Color{255, 255, 255}},
+		{&Color{}, Color{}},
+	}
+
+	for _, test := range tests {
+		buf, err := NewImage(readImage("test.png")).Process(Options{
+			Type: JPEG,
+			Mask: Mask{Shape: MASK_CIRCLE, Background: test.background},
+		})
+		if err != nil {
+			t.Fatalf("Cannot mask the image: %s", err)
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("Cannot decode the image: %s", err)
+		}
+
+		// Corners are outside of the circle
+		r, g, b, _ := img.At(0, 0).RGBA()
+		corner := Color{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+		if ColorDistance(corner, test.corner) > 3 {
+			t.Errorf("Invalid masked corner: %v != %v", corner, test.corner)
+		}
+	}
+}
+
+func TestMaskImage(t *testing.T) {
+	buf, err := NewImage(readImage("test.png")).Mask(Mask{Buf: documentImage(200, 150)})
+	if err != nil {
+		t.Fatalf("Cannot mask the image: %s", err)
+	}
+	if err := assertSize(buf, 400, 300); err != nil {
+		t.Error(err)
+	}
+
+	Write("fixtures/test_mask_image_out.png", buf)
+}
@@ -19,6 +19,10 @@ const (
 	EAST
 	SOUTH
 	WEST
+	NORTHEAST
+	SOUTHEAST
+	SOUTHWEST
+	NORTHWEST
 )
 
 type Interpolator int
@@ -75,20 +79,80 @@ const (
 
 const WATERMARK_FONT = "sans 10"
 
+// Position of the text watermark, or of its first tile, unless positioned
+const watermarkOffset = 100
+
 // Color represents a traditional RGB color scheme
 type Color struct {
 	R, G, B uint8
 }
 
+// backgroundColor returns the colour images are flattened onto, white if unset
+func backgroundColor(c *Color) Color {
+	if c == nil {
+		return Color{255, 255, 255}
+	}
+	return *c
+}
+
+// Text alignment of multi-line watermarks
+type Align int
+
+const (
+	ALIGN_LEFT   Align = C.VIPS_ALIGN_LOW
+	ALIGN_CENTRE Align = C.VIPS_ALIGN_CENTRE
+	ALIGN_RIGHT  Align = C.VIPS_ALIGN_HIGH
+)
+
+// Watermark renders a text over the image, tiled by default with Margin pixels
+// between the copies, or once with NoReplicate. The text, or the first tile,
+// starts at the fixed 100,100 position unless Positioned is true:
+// tiles then start at the Left and Top position, and the single text is
+// placed by Gravity and moved by the Left and Top offsets, like WatermarkImage.
 type Watermark struct {
 	Width       int
 	DPI         int
 	Margin      int
 	Opacity     float32
 	NoReplicate bool
+	Positioned  bool
 	Text        string
 	Font        string
-	Background  Color
+	// Path of a font file to load, such as a TTF, whose family is selected with Font.
+	// Requires libvips 8.9+
+	FontFile string
+	// Text colour
+	Color Color
+	// Deprecated: use Color instead, which takes precedence
+	Background Color
+	Gravity    Gravity
+	Left       int
+	Top        int
+	// Clockwise rotation of the text in degrees, such as -45 for a diagonal
+	Angle float64
+	Align Align
+	// Extra space between the text lines in points. Requires libvips 8.9+
+	LineSpacing int
+}
+
+// WatermarkImage overlays an image, such as a PNG logo, over the processed image.
+// The watermark is placed by Gravity, moved inwards from the gravity edges
+// by the Left and Top offsets (or right and down if centred on that axis),
+// or tiled over the whole image from the Left and Top position if Tile is true.
+// Transparent watermarks are scaled with premultiplied alpha.
+// As Buf is a slice, Options values can't be compared with ==
+type WatermarkImage struct {
+	Buf     []byte
+	Gravity Gravity
+	Left    int
+	Top     int
+	// Watermark width relative to the image width (0.2 for 20%), original size if zero
+	Width float64
+	// Opacity from 0 to 1, opaque if zero
+	Opacity float32
+	Tile    bool
+	// Space between tiles, in pixels
+	Spacing int
 }
 
 type GaussianBlur struct {
@@ -96,6 +160,114 @@ type GaussianBlur struct {
 	MinAmpl float64
 }
 
+// Sharpen defines an unsharp mask applied to the L band in LAB space,
+// see vips_sharpen for the meaning of every parameter.
+// Zero values use the libvips defaults (sigma 0.5, x1 2, y2 10, y3 20, m1 0, m2 3).
+// Amount is a simpler strength control, used when M1 and M2 are not defined:
+// jagged areas are sharpened with a slope of 3 * Amount
+type Sharpen struct {
+	Sigma  float64
+	X1     float64
+	Y2     float64
+	Y3     float64
+	M1     float64
+	M2     float64
+	Amount float64
+}
+
+// Adjust defines colour adjustments. Zero values leave the image unchanged.
+// Brightness, Contrast and Saturation are relative changes, from -1 to 1
+// (e.g. 0.2 for 20% brighter). Gamma is the exponent applied to the
+// normalised pixel values as 1 / Gamma, so values above 1 lighten mid-tones.
+// Hue rotates the colours by the given angle in degrees
+type Adjust struct {
+	Brightness float64
+	Contrast   float64
+	Gamma      float64
+	Saturation float64
+	Hue        float64
+}
+
+// Duotone maps the image luminance from the Shadow to the Highlight colour
+type Duotone struct {
+	Shadow    Color
+	Highlight Color
+}
+
+// Effects defines the filters applied over the 8 bits sRGB image,
+// preserving its alpha channel. Filters can be combined and are applied
+// in the order of the fields. The output colourspace is still defined
+// by Options.Interpretation, sRGB by default.
+type Effects struct {
+	Greyscale bool
+	Sepia     bool
+	// Tint the image with two colours, disabled if both are black
+	Duotone Duotone
+	// Number of levels per band, disabled if lower than 2
+	Posterise int
+	// Luminance from which pixels become white, others become black
+	// (from 1 to 255, disabled if zero)
+	Threshold float64
+	Invert    bool
+}
+
+// Rotation rotates the image clockwise by an arbitrary angle in degrees,
+// once resized. The canvas is expanded to fit the rotated image, unless Crop
+// is true, in which case the output keeps the size of the resized image.
+// Uncovered areas are filled with the Background colour, black by default,
+// or with transparent pixels if Transparent is true (the output type must
+// support alpha), in which case Background is ignored.
+// Unlike Options.Rotate, which is rounded down to a multiple of 90 degrees,
+// any angle is applied as is.
+// Requires libvips 8.6+, except for multiples of 90 degrees
+type Rotation struct {
+	Angle       float64
+	Background  Color
+	Transparent bool
+	Crop        bool
+}
+
+// AlphaOperation changes the alpha channel of the processed image
+type AlphaOperation int
+
+const (
+	ALPHA_KEEP AlphaOperation = iota
+	// Add an opaque alpha channel, if missing
+	ALPHA_ADD
+	// Remove the alpha channel, without flattening the image
+	ALPHA_REMOVE
+	// Replace the image by its alpha channel, as greyscale
+	ALPHA_EXTRACT
+)
+
+// Trim removes the uniform borders of the image, before any other operation
+type Trim struct {
+	Enabled bool
+	// Maximum distance to the background colour of the border pixels, 10 by default
+	Threshold float64
+	// Colour of the borders, ignored if AutoBackground is true
+	Background Color
+	// Use the top-left pixel colour as the borders colour
+	AutoBackground bool
+}
+
+// Area is a rectangular region of an image, in pixels
+type Area struct {
+	Left   int
+	Top    int
+	Width  int
+	Height int
+}
+
+// AutoQuality picks the lowest JPEG or WebP encoding quality whose
+// structural similarity (SSIM) with the processed image reaches the threshold.
+// MinQuality and MaxQuality default to 30 and 95 respectively
+type AutoQuality struct {
+	SSIM       float64
+	MinQuality int
+	MaxQuality int
+}
+
 type Options struct {
 	Height         int
 	Width          int
@@ -106,6 +278,7 @@ type Options struct {
 	Extend         int
 	Quality        int
 	Compression    int
+	MaxBytes       int
 	Zoom           int
 	Crop           bool
 	Enlarge        bool
@@ -116,11 +289,23 @@ type Options struct {
 	NoAutoRotate   bool
 	NoProfile      bool
 	Interlace      bool
-	Rotate         Angle
+	Rotate         Angle // Rounded down to a multiple of 90 degrees, see Rotation for any angle
 	Gravity        Gravity
 	Watermark      Watermark
+	WatermarkImage WatermarkImage
 	Type           ImageType
 	Interpolator   Interpolator
 	Interpretation Interpretation
 	GaussianBlur   GaussianBlur
+	Sharpen        Sharpen
+	Adjust         Adjust
+	Effects        Effects
+	Trim           Trim
+	Rotation       Rotation // Any angle, applied after Rotate and the resize
+	AutoQuality    AutoQuality
+	Composite      []Layer
+	Mask           Mask
+	Flatten        bool
+	Background     *Color // Flatten colour, white if nil
+	Alpha          AlphaOperation
 }
@@ -0,0 +1,283 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PipelineError is returned when a pipeline step fails.
+// Step is the zero-based index of the failing step in the recorded chain.
+type PipelineError struct {
+	Step int
+	Name string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline step %d (%s) failed: %s", e.Step, e.Name, e.Err)
+}
+
+type pipelineStep struct {
+	name    string
+	options Options
+}
+
+// Pipeline records a chain of operations which are applied on the same
+// libvips image graph, decoding the input and encoding the output only once.
+// Nothing is processed until Execute() is called.
+type Pipeline struct {
+	buffer []byte
+	image  *Image
+	steps  []pipelineStep
+}
+
+// Creates a new pipeline of operations for the given image buffer
+func NewPipeline(buf []byte) *Pipeline {
+	return &Pipeline{buffer: buf}
+}
+
+// Creates a new pipeline of operations for the current image.
+// The image buffer is replaced by the pipeline output once executed
+func (i *Image) Pipeline() *Pipeline {
+	return &Pipeline{buffer: i.buffer, image: i}
+}
+
+// Resize the image to fixed width and height
+func (p *Pipeline) Resize(width, height int) *Pipeline {
+	return p.add("resize", Options{Width: width, Height: height, Embed: true})
+}
+
+// Force resize with custom size (aspect ratio won't be maintained)
+func (p *Pipeline) ForceResize(width, height int) *Pipeline {
+	return p.add("force resize", Options{Width: width, Height: height, Force: true})
+}
+
+// Resize the image to fixed width and height with additional crop transformation
+func (p *Pipeline) ResizeAndCrop(width, height int) *Pipeline {
+	return p.add("resize and crop", Options{Width: width, Height: height, Embed: true, Crop: true})
+}
+
+// Extract area from the by X/Y axis
+func (p *Pipeline) Extract(top, left, width, height int) *Pipeline {
+	return p.add("extract", Options{Top: top, Left: left, AreaWidth: width, AreaHeight: height})
+}
+
+// Enlarge the image by width and height. Aspect ratio is maintained
+func (p *Pipeline) Enlarge(width, height int) *Pipeline {
+	return p.add("enlarge", Options{Width: width, Height: height, Enlarge: true})
+}
+
+// Enlarge the image by width and height with additional crop transformation
+func (p *Pipeline) EnlargeAndCrop(width, height int) *Pipeline {
+	return p.add("enlarge and crop", Options{Width: width, Height: height, Enlarge: true, Crop: true})
+}
+
+// Crop the image to the exact size specified
+func (p *Pipeline) Crop(width, height int, gravity Gravity) *Pipeline {
+	return p.add("crop", Options{Width: width, Height: height, Gravity: gravity, Crop: true})
+}
+
+// Crop an image by width (auto height)
+func (p *Pipeline) CropByWidth(width int) *Pipeline {
+	return p.add("crop by width", Options{Width: width, Crop: true})
+}
+
+// Crop an image by height (auto width)
+func (p *Pipeline) CropByHeight(height int) *Pipeline {
+	return p.add("crop by height", Options{Height: height, Crop: true})
+}
+
+// Thumbnail the image by the a given width by aspect ratio 4:4
+func (p *Pipeline) Thumbnail(pixels int) *Pipeline {
+	return p.add("thumbnail", Options{Width: pixels, Height: pixels, Crop: true, Quality: 95})
+}
+
+// Add text as watermark on the given image
+func (p *Pipeline) Watermark(w Watermark) *Pipeline {
+	return p.add("watermark", Options{Watermark: w})
+}
+
+// Add an image, such as a logo, as watermark on the given image
+func (p *Pipeline) WatermarkImage(w WatermarkImage) *Pipeline {
+	return p.add("watermark image", Options{WatermarkImage: w})
+}
+
+// Composite the layers over the image
+func (p *Pipeline) Composite(layers []Layer) *Pipeline {
+	return p.add("composite", Options{Composite: layers})
+}
+
+// Mask the image with a shape or a greyscale image
+func (p *Pipeline) Mask(m Mask) *Pipeline {
+	return p.add("mask", Options{Mask: m})
+}
+
+// Zoom the image by the given factor
+func (p *Pipeline) Zoom(factor int) *Pipeline {
+	return p.add("zoom", Options{Zoom: factor})
+}
+
+// Rotate the image by given angle degrees (0, 90, 180 or 270)
+func (p *Pipeline) Rotate(a Angle) *Pipeline {
+	return p.add("rotate", Options{Rotate: a})
+}
+
+// Rotate the image clockwise by an arbitrary angle in degrees
+func (p *Pipeline) RotateBy(r Rotation) *Pipeline {
+	return p.add("rotate by", Options{Rotation: r})
+}
+
+// Flip the image about the vertical Y axis
+func (p *Pipeline) Flip() *Pipeline {
+	return p.add("flip", Options{Flip: true})
+}
+
+// Flop the image about the horizontal X axis
+func (p *Pipeline) Flop() *Pipeline {
+	return p.add("flop", Options{Flop: true})
+}
+
+// Convert image to another format
+func (p *Pipeline) Convert(t ImageType) *Pipeline {
+	return p.add("convert", Options{Type: t})
+}
+
+// Colour space conversion
+func (p *Pipeline) Colourspace(c Interpretation) *Pipeline {
+	return p.add("colourspace", Options{Interpretation: c})
+}
+
+// Adjust the image colours (brightness, contrast, gamma, saturation and hue)
+func (p *Pipeline) Adjust(a Adjust) *Pipeline {
+	return p.add("adjust", Options{Adjust: a})
+}
+
+// Apply filter effects (greyscale, sepia, duotone, posterise, threshold, invert)
+func (p *Pipeline) Effects(e Effects) *Pipeline {
+	return p.add("effects", Options{Effects: e})
+}
+
+// Trim the uniform borders of the image
+func (p *Pipeline) Trim(t Trim) *Pipeline {
+	t.Enabled = true
+	return p.add("trim", Options{Trim: t})
+}
+
+// Flatten the alpha channel onto the background colour
+func (p *Pipeline) Flatten(background Color) *Pipeline {
+	return p.add("flatten", Options{Flatten: true, Background: &background})
+}
+
+// Add an opaque alpha channel to the image, if missing
+func (p *Pipeline) AddAlpha() *Pipeline {
+	return p.add("add alpha", Options{Alpha: ALPHA_ADD})
+}
+
+// Remove the alpha channel of the image, without flattening it
+func (p *Pipeline) RemoveAlpha() *Pipeline {
+	return p.add("remove alpha", Options{Alpha: ALPHA_REMOVE})
+}
+
+// Replace the image by its alpha channel, as a greyscale image
+func (p *Pipeline) ExtractAlpha() *Pipeline {
+	return p.add("extract alpha", Options{Alpha: ALPHA_EXTRACT, Interpretation: INTERPRETATION_B_W})
+}
+
+// Transform the image by custom options
+func (p *Pipeline) Process(o Options) *Pipeline {
+	return p.add("process", o)
+}
+
+// Execute runs every recorded step over a single decoded image
+// and returns the encoded output.
+// Encoding options (type, quality, compression, max bytes, auto quality,
+// interlace, profile and interpretation) are taken from the last step defining them.
+func (p *Pipeline) Execute() ([]byte, error) {
+	defer C.vips_thread_shutdown()
+
+	if len(p.buffer) == 0 {
+		return nil, errors.New("Image buffer is empty")
+	}
+
+	image, imageType, err := vipsRead(p.buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	output := Options{}
+	for _, step := range p.steps {
+		mergeSaveOptions(&output, step.options)
+	}
+	applyDefaults(&output, imageType)
+
+	if IsTypeSupported(output.Type) == false {
+		C.g_object_unref(C.gpointer(image))
+		return nil, errors.New("Unsupported image output type")
+	}
+
+	for index, step := range p.steps {
+		buf, o := p.buffer, step.options
+
+		// Shrink-on-load and EXIF based auto rotation
+		// only make sense over the original image
+		if index > 0 {
+			buf = nil
+			o.NoAutoRotate = true
+		}
+
+		debug("Pipeline step %d (%s): %#v", index, step.name, o)
+
+		image, err = processImage(image, buf, imageType, o)
+		if err != nil {
+			return nil, &PipelineError{Step: index, Name: step.name, Err: err}
+		}
+	}
+
+	buf, err := vipsSave(image, getSaveOptions(output))
+	if err != nil {
+		return nil, &PipelineError{Step: len(p.steps), Name: "save", Err: err}
+	}
+
+	if p.image != nil {
+		p.image.buffer = buf
+	}
+
+	return buf, nil
+}
+
+func (p *Pipeline) add(name string, o Options) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{name, o})
+	return p
+}
+
+func mergeSaveOptions(out *Options, o Options) {
+	if o.Quality > 0 {
+		out.Quality = o.Quality
+	}
+	if o.Compression > 0 {
+		out.Compression = o.Compression
+	}
+	if o.Type != UNKNOWN {
+		out.Type = o.Type
+	}
+	if o.MaxBytes > 0 {
+		out.MaxBytes = o.MaxBytes
+	}
+	if o.AutoQuality.SSIM > 0 {
+		out.AutoQuality = o.AutoQuality
+	}
+	if o.Interpretation != 0 {
+		out.Interpretation = o.Interpretation
+	}
+	if o.Background != nil {
+		out.Background = o.Background
+	}
+	out.Interlace = out.Interlace || o.Interlace
+	out.NoProfile = out.NoProfile || o.NoProfile
+}
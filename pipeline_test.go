@@ -0,0 +1,109 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	buf, err := NewPipeline(readImage("test.jpg")).
+		Extract(100, 100, 800, 600).
+		Resize(400, 300).
+		Rotate(D90).
+		Watermark(Watermark{
+			Text:       "Copy me if you can",
+			Opacity:    0.5,
+			Width:      200,
+			DPI:        100,
+			Background: Color{255, 255, 255},
+		}).
+		Execute()
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	err = assertSize(buf, 300, 400)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if DetermineImageType(buf) != JPEG {
+		t.Fatal("Image is not jpeg")
+	}
+
+	Write("fixtures/test_pipeline_out.jpg", buf)
+}
+
+func TestPipelineConvert(t *testing.T) {
+	image := initImage("test.jpg")
+
+	buf, err := image.Pipeline().CropByWidth(300).Flip().Convert(PNG).Execute()
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	if DetermineImageType(buf) != PNG {
+		t.Fatal("Image is not png")
+	}
+	if DetermineImageType(image.Image()) != PNG {
+		t.Fatal("Image buffer was not updated")
+	}
+
+	size, _ := Size(buf)
+	if size.Width != 300 {
+		t.Fatalf("Invalid width size: %d", size.Width)
+	}
+}
+
+func TestPipelineMaxBytes(t *testing.T) {
+	full, err := NewPipeline(readImage("test.jpg")).Resize(800, 600).Execute()
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	// The budget of an earlier step is kept by later steps
+	buf, err := NewPipeline(readImage("test.jpg")).
+		Process(Options{MaxBytes: 20000}).
+		Resize(800, 600).
+		Convert(JPEG).
+		Execute()
+	if err != nil {
+		t.Fatalf("Cannot process the image: %s", err)
+	}
+
+	if len(full) <= 20000 {
+		t.Fatalf("Unbounded image is already within the budget: %d bytes", len(full))
+	}
+	if len(buf) > 20000 {
+		t.Errorf("Image exceeds the size budget: %d bytes", len(buf))
+	}
+	if err := assertSize(buf, 800, 600); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	_, err := NewPipeline(readImage("test.jpg")).
+		Resize(400, 300).
+		Extract(10, 10, 1000, 1000).
+		Flip().
+		Execute()
+	if err == nil {
+		t.Fatal("Expected pipeline error")
+	}
+
+	perr, ok := err.(*PipelineError)
+	if !ok {
+		t.Fatalf("Invalid error type: %#v", err)
+	}
+	if perr.Step != 1 || perr.Name != "extract" {
+		t.Fatalf("Invalid failing step: %d (%s)", perr.Step, perr.Name)
+	}
+}
+
+func BenchmarkPipelineJpeg(b *testing.B) {
+	buf := readImage("test.jpg")
+
+	for n := 0; n < b.N; n++ {
+		NewPipeline(buf).Extract(100, 100, 800, 600).Resize(400, 300).Rotate(D90).Execute()
+	}
+}
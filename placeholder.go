@@ -0,0 +1,465 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"math"
+	"strings"
+)
+
+const (
+	// Largest side of the thumbnail encoded by BlurHash
+	blurHashSampleSize = 32
+	// Largest side of the thumbnail encoded by ThumbHash
+	thumbHashSampleSize = 100
+	// Default largest side of the data URI placeholders
+	lqipDefaultSize = 16
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// LQIPOptions defines the tiny placeholder image generated by LQIP
+type LQIPOptions struct {
+	// Largest side of the placeholder, 16 pixels by default
+	Size int
+	// Output format, JPEG by default. WEBP is also supported
+	Type ImageType
+	// Encoding quality, 40 by default
+	Quality int
+	// Gaussian blur sigma, 1 by default. Use a negative value to disable it
+	Blur float64
+}
+
+// BlurHash encodes the image as a BlurHash string, made of
+// xComponents by yComponents DCT components (from 1 to 9 each).
+// The image is shrunk on load before being encoded.
+// See: https://blurha.sh
+func BlurHash(buf []byte, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", errors.New("BlurHash components must be between 1 and 9")
+	}
+
+	rgba, width, height, err := readPlaceholderRGBA(buf, blurHashSampleSize)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeBlurHash(rgba, width, height, xComponents, yComponents), nil
+}
+
+// DecodeBlurHash renders the given BlurHash as a PNG image of the given size.
+// Punch adjusts the contrast of the result, 1 by default
+func DecodeBlurHash(hash string, width, height int, punch float64) ([]byte, error) {
+	defer C.vips_thread_shutdown()
+
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("Invalid BlurHash image size")
+	}
+
+	pixels, err := decodeBlurHash(hash, width, height, punch)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := vipsImageFromPixels(pixels, width, height, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	return vipsSave(image, vipsSaveOptions{Type: PNG, Compression: 6})
+}
+
+// ThumbHash encodes the image as a ThumbHash, including its aspect
+// ratio and alpha channel. The image is shrunk on load before being encoded.
+// See: https://evanw.github.io/thumbhash
+func ThumbHash(buf []byte) ([]byte, error) {
+	rgba, width, height, err := readPlaceholderRGBA(buf, thumbHashSampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeThumbHash(rgba, width, height), nil
+}
+
+// LQIP returns a tiny blurred version of the image as a base64 data URI,
+// ready to be used as an inline placeholder while the image is loading
+func LQIP(buf []byte, o LQIPOptions) (string, error) {
+	if o.Size == 0 {
+		o.Size = lqipDefaultSize
+	}
+	if o.Type == UNKNOWN {
+		o.Type = JPEG
+	}
+	if o.Quality == 0 {
+		o.Quality = 40
+	}
+	if o.Blur == 0 {
+		o.Blur = 1
+	}
+
+	if o.Size < 0 {
+		return "", errors.New("Invalid placeholder size")
+	}
+	if o.Type != JPEG && o.Type != WEBP {
+		return "", errors.New("Placeholders can only be encoded as JPEG or WEBP")
+	}
+
+	width, height, err := placeholderSize(buf, o.Size)
+	if err != nil {
+		return "", err
+	}
+
+	opts := Options{Width: width, Height: height, Type: o.Type, Quality: o.Quality, NoProfile: true}
+	if o.Blur > 0 {
+		opts.GaussianBlur = GaussianBlur{Sigma: o.Blur}
+	}
+
+	out, err := Resize(buf, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return "data:" + getImageMimeType(o.Type) + ";base64," + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Get the BlurHash of the image
+func (i *Image) BlurHash(xComponents, yComponents int) (string, error) {
+	return BlurHash(i.buffer, xComponents, yComponents)
+}
+
+// Get the ThumbHash of the image
+func (i *Image) ThumbHash() ([]byte, error) {
+	return ThumbHash(i.buffer)
+}
+
+// Get a tiny blurred placeholder of the image as a data URI
+func (i *Image) LQIP(o LQIPOptions) (string, error) {
+	return LQIP(i.buffer, o)
+}
+
+// placeholderSize returns the target width or height fitting
+// the largest side of the image in the given size
+func placeholderSize(buf []byte, size int) (int, int, error) {
+	metadata, err := Metadata(buf)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	width, height := metadata.Size.Width, metadata.Size.Height
+	if width >= height {
+		return size, 0, nil
+	}
+	return 0, size, nil
+}
+
+func readPlaceholderRGBA(buf []byte, size int) ([]byte, int, int, error) {
+	width, height, err := placeholderSize(buf, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return readRGBA(buf, width, height)
+}
+
+func encodeBlurHash(rgba []byte, width, height, xComponents, yComponents int) string {
+	factors := make([][3]float64, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				fy := math.Cos(math.Pi * float64(j*y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := normalisation * fy * math.Cos(math.Pi*float64(i*x)/float64(width))
+					p := rgba[(y*width+x)*4:]
+					for c := 0; c < 3; c++ {
+						factor[c] += basis * srgbToLinear(p[c])
+					}
+				}
+			}
+
+			scale := 1 / float64(width*height)
+			for c := 0; c < 3; c++ {
+				factor[c] *= scale
+			}
+			factors[j*xComponents+i] = factor
+		}
+	}
+
+	var hash bytes.Buffer
+	hash.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	maximum := 1.0
+	if len(factors) > 1 {
+		actual := 0.0
+		for _, factor := range factors[1:] {
+			for c := 0; c < 3; c++ {
+				actual = math.Max(actual, math.Abs(factor[c]))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actual*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		hash.WriteString(encodeBase83(quantised, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	dc := factors[0]
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+
+	for _, factor := range factors[1:] {
+		var quant [3]int
+		for c := 0; c < 3; c++ {
+			quant[c] = int(math.Max(0, math.Min(18, math.Floor(signPow(factor[c]/maximum, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quant[0]*19*19+quant[1]*19+quant[2], 2))
+	}
+
+	return hash.String()
+}
+
+func decodeBlurHash(hash string, width, height int, punch float64) ([]byte, error) {
+	if len(hash) < 6 {
+		return nil, errors.New("Invalid BlurHash length")
+	}
+	if punch == 0 {
+		punch = 1
+	}
+
+	sizeFlag, err := decodeBase83(hash[:1])
+	if err != nil {
+		return nil, err
+	}
+	xComponents, yComponents := sizeFlag%9+1, sizeFlag/9+1
+	if len(hash) != 4+2*xComponents*yComponents {
+		return nil, errors.New("Invalid BlurHash length")
+	}
+
+	quantised, err := decodeBase83(hash[1:2])
+	if err != nil {
+		return nil, err
+	}
+	maximum := float64(quantised+1) / 166 * punch
+
+	colors := make([][3]float64, xComponents*yComponents)
+	for index := range colors {
+		if index == 0 {
+			value, err := decodeBase83(hash[2:6])
+			if err != nil {
+				return nil, err
+			}
+			colors[0] = [3]float64{
+				srgbToLinear(uint8(value >> 16)),
+				srgbToLinear(uint8(value >> 8)),
+				srgbToLinear(uint8(value)),
+			}
+			continue
+		}
+
+		value, err := decodeBase83(hash[4+index*2 : 6+index*2])
+		if err != nil {
+			return nil, err
+		}
+		quant := [3]int{value / (19 * 19), value / 19 % 19, value % 19}
+		for c := 0; c < 3; c++ {
+			colors[index][c] = signPow(float64(quant[c]-9)/9, 2) * maximum
+		}
+	}
+
+	pixels := make([]byte, width*height*3)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var color [3]float64
+			for j := 0; j < yComponents; j++ {
+				fy := math.Cos(math.Pi * float64(y*j) / float64(height))
+				for i := 0; i < xComponents; i++ {
+					basis := fy * math.Cos(math.Pi*float64(x*i)/float64(width))
+					for c := 0; c < 3; c++ {
+						color[c] += colors[j*xComponents+i][c] * basis
+					}
+				}
+			}
+			for c := 0; c < 3; c++ {
+				pixels[(y*width+x)*3+c] = uint8(linearToSRGB(color[c]))
+			}
+		}
+	}
+
+	return pixels, nil
+}
+
+func encodeThumbHash(rgba []byte, width, height int) []byte {
+	round := func(x float64) int {
+		return int(math.Floor(x + 0.5))
+	}
+
+	// Average colour, weighted by opacity
+	var avgR, avgG, avgB, avgA float64
+	for i := 0; i < width*height; i++ {
+		alpha := float64(rgba[i*4+3]) / 255
+		avgR += alpha / 255 * float64(rgba[i*4])
+		avgG += alpha / 255 * float64(rgba[i*4+1])
+		avgB += alpha / 255 * float64(rgba[i*4+2])
+		avgA += alpha
+	}
+	if avgA > 0 {
+		avgR /= avgA
+		avgG /= avgA
+		avgB /= avgA
+	}
+
+	hasAlpha := avgA < float64(width*height)
+	limit := 7
+	if hasAlpha {
+		// Use fewer luminance bits if there's alpha
+		limit = 5
+	}
+	longest := math.Max(float64(width), float64(height))
+	lx := int(math.Max(1, float64(round(float64(limit*width)/longest))))
+	ly := int(math.Max(1, float64(round(float64(limit*height)/longest))))
+
+	// Convert to LPQA (luminance, yellow-blue, red-green, alpha), composited over the average colour
+	l := make([]float64, width*height)
+	p := make([]float64, width*height)
+	q := make([]float64, width*height)
+	a := make([]float64, width*height)
+	for i := range l {
+		alpha := float64(rgba[i*4+3]) / 255
+		r := avgR*(1-alpha) + alpha/255*float64(rgba[i*4])
+		g := avgG*(1-alpha) + alpha/255*float64(rgba[i*4+1])
+		b := avgB*(1-alpha) + alpha/255*float64(rgba[i*4+2])
+		l[i] = (r + g + b) / 3
+		p[i] = (r+g)/2 - b
+		q[i] = r - g
+		a[i] = alpha
+	}
+
+	encodeChannel := func(channel []float64, nx, ny int) (float64, []float64, float64) {
+		dc, scale := 0.0, 0.0
+		var ac []float64
+		fx := make([]float64, width)
+		for cy := 0; cy < ny; cy++ {
+			for cx := 0; cx*ny < nx*(ny-cy); cx++ {
+				for x := 0; x < width; x++ {
+					fx[x] = math.Cos(math.Pi / float64(width) * float64(cx) * (float64(x) + 0.5))
+				}
+				f := 0.0
+				for y := 0; y < height; y++ {
+					fy := math.Cos(math.Pi / float64(height) * float64(cy) * (float64(y) + 0.5))
+					for x := 0; x < width; x++ {
+						f += channel[y*width+x] * fx[x] * fy
+					}
+				}
+				f /= float64(width * height)
+				if cx > 0 || cy > 0 {
+					ac = append(ac, f)
+					scale = math.Max(scale, math.Abs(f))
+				} else {
+					dc = f
+				}
+			}
+		}
+		if scale > 0 {
+			for i := range ac {
+				ac[i] = 0.5 + 0.5/scale*ac[i]
+			}
+		}
+		return dc, ac, scale
+	}
+
+	lDC, lAC, lScale := encodeChannel(l, maxInt(3, lx), maxInt(3, ly))
+	pDC, pAC, pScale := encodeChannel(p, 3, 3)
+	qDC, qAC, qScale := encodeChannel(q, 3, 3)
+	channels := [][]float64{lAC, pAC, qAC}
+
+	isLandscape := width > height
+	header24 := round(63*lDC) | round(31.5+31.5*pDC)<<6 | round(31.5+31.5*qDC)<<12 | round(31*lScale)<<18
+	header16 := round(63*pScale)<<3 | round(63*qScale)<<9
+	if hasAlpha {
+		header24 |= 1 << 23
+	}
+	if isLandscape {
+		header16 |= ly | 1<<15
+	} else {
+		header16 |= lx
+	}
+
+	hash := []byte{byte(header24), byte(header24 >> 8), byte(header24 >> 16), byte(header16), byte(header16 >> 8)}
+	if hasAlpha {
+		aDC, aAC, aScale := encodeChannel(a, 5, 5)
+		hash = append(hash, byte(round(15*aDC)|round(15*aScale)<<4))
+		channels = append(channels, aAC)
+	}
+
+	// Two AC factors of 4 bits per byte
+	start, index := len(hash), 0
+	for _, ac := range channels {
+		for _, f := range ac {
+			if start+index/2 >= len(hash) {
+				hash = append(hash, 0)
+			}
+			hash[start+index/2] |= byte(round(15*f) << uint((index&1)*4))
+			index++
+		}
+	}
+
+	return hash
+}
+
+func encodeBase83(value, length int) string {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = base83Chars[value%83]
+		value /= 83
+	}
+	return string(out)
+}
+
+func decodeBase83(s string) (int, error) {
+	value := 0
+	for i := 0; i < len(s); i++ {
+		index := strings.IndexByte(base83Chars, s[i])
+		if index == -1 {
+			return 0, errors.New("Invalid BlurHash character")
+		}
+		value = value*83 + index
+	}
+	return value, nil
+}
+
+func srgbToLinear(v uint8) float64 {
+	x := float64(v) / 255
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	x := math.Max(0, math.Min(1, v))
+	if x <= 0.0031308 {
+		return int(x*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(x, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,138 @@
+package bimg
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestBlurHash(t *testing.T) {
+	files := []string{"test.jpg", "test.png", "transparent.png"}
+
+	for _, file := range files {
+		hash, err := initImage(file).BlurHash(4, 3)
+		if err != nil {
+			t.Fatalf("Cannot encode the BlurHash of %s: %s", file, err)
+		}
+		if len(hash) != 4+2*4*3 {
+			t.Fatalf("Invalid BlurHash length: %s", hash)
+		}
+
+		buf, err := DecodeBlurHash(hash, 32, 24, 1)
+		if err != nil {
+			t.Fatalf("Cannot decode the BlurHash %s: %s", hash, err)
+		}
+		if DetermineImageType(buf) != PNG {
+			t.Fatal("Image is not png")
+		}
+		if err := assertSize(buf, 32, 24); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBlurHashInvalidComponents(t *testing.T) {
+	if _, err := initImage("test.jpg").BlurHash(0, 10); err == nil {
+		t.Fatal("Invalid components must fail")
+	}
+}
+
+func TestBlurHashRoundTrip(t *testing.T) {
+	width, height := 16, 8
+	rgba := make([]byte, width*height*4)
+	for i := 0; i < width*height; i++ {
+		copy(rgba[i*4:], []byte{200, 100, 50, 255})
+	}
+
+	hash := encodeBlurHash(rgba, width, height, 3, 3)
+	if hash[:1] != "K" {
+		t.Fatalf("Invalid BlurHash size flag: %s", hash)
+	}
+
+	if _, err := decodeBlurHash(hash, 4, 4, 1); err != nil {
+		t.Fatalf("Cannot decode the BlurHash %s: %s", hash, err)
+	}
+
+	// A single DC component keeps the exact average colour
+	pixels, err := decodeBlurHash(encodeBlurHash(rgba, width, height, 1, 1), 4, 4, 1)
+	if err != nil {
+		t.Fatalf("Cannot decode the BlurHash: %s", err)
+	}
+	for i := 0; i < len(pixels); i += 3 {
+		if pixels[i] != 200 || pixels[i+1] != 100 || pixels[i+2] != 50 {
+			t.Fatalf("Invalid decoded colour: %v", pixels[i:i+3])
+		}
+	}
+
+	if _, err := decodeBlurHash(hash[:len(hash)-1], 4, 4, 1); err == nil {
+		t.Fatal("Truncated BlurHash must fail")
+	}
+	if _, err := decodeBlurHash("\""+hash[1:], 4, 4, 1); err == nil {
+		t.Fatal("Invalid BlurHash characters must fail")
+	}
+}
+
+func TestThumbHash(t *testing.T) {
+	opaque, err := initImage("test.jpg").ThumbHash()
+	if err != nil {
+		t.Fatalf("Cannot encode the ThumbHash: %s", err)
+	}
+	if len(opaque) < 5 || opaque[2]&0x80 != 0 {
+		t.Fatalf("Invalid opaque ThumbHash: %v", opaque)
+	}
+	if opaque[4]&0x80 == 0 {
+		t.Fatal("ThumbHash of a landscape image must be flagged as landscape")
+	}
+
+	transparent, err := initImage("transparent.png").ThumbHash()
+	if err != nil {
+		t.Fatalf("Cannot encode the ThumbHash: %s", err)
+	}
+	if len(transparent) < 6 || transparent[2]&0x80 == 0 {
+		t.Fatalf("Invalid transparent ThumbHash: %v", transparent)
+	}
+}
+
+func TestThumbHashLength(t *testing.T) {
+	width, height := 4, 4
+	rgba := make([]byte, width*height*4)
+	for i := 0; i < width*height; i++ {
+		copy(rgba[i*4:], []byte{byte(i * 16), 128, 64, 255})
+	}
+
+	// 5 header bytes, then 27 luminance and 2x5 chrominance AC factors of 4 bits
+	if hash := encodeThumbHash(rgba, width, height); len(hash) != 24 {
+		t.Fatalf("Invalid ThumbHash length: %d", len(hash))
+	}
+}
+
+func TestLQIP(t *testing.T) {
+	for _, imageType := range []ImageType{JPEG, WEBP} {
+		uri, err := initImage("test.jpg").LQIP(LQIPOptions{Type: imageType})
+		if err != nil {
+			t.Fatalf("Cannot generate the placeholder: %s", err)
+		}
+
+		prefix := "data:" + getImageMimeType(imageType) + ";base64,"
+		if !strings.HasPrefix(uri, prefix) {
+			t.Fatalf("Invalid data URI: %s", uri)
+		}
+
+		buf, err := base64.StdEncoding.DecodeString(uri[len(prefix):])
+		if err != nil {
+			t.Fatalf("Invalid base64 data: %s", err)
+		}
+		if DetermineImageType(buf) != imageType {
+			t.Fatal("Invalid placeholder type")
+		}
+		if err := assertSize(buf, 16, 10); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLQIPInvalidType(t *testing.T) {
+	if _, err := LQIP(readImage("test.jpg"), LQIPOptions{Type: PNG}); err == nil {
+		t.Fatal("PNG placeholders must fail")
+	}
+}
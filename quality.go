@@ -0,0 +1,197 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import "fmt"
+
+const (
+	minQuality        = 1
+	maxWebpEffort     = 6
+	maxPngCompression = 9
+	autoMinQuality    = 30
+	autoMaxQuality    = 95
+)
+
+// Palette based PNG encoding requires libvips 8.7+
+const hasPalettePNG = C.VIPS_MAJOR_VERSION > 8 || (C.VIPS_MAJOR_VERSION == 8 && C.VIPS_MINOR_VERSION >= 7)
+
+// AutoQualityResult holds the image encoded by ResizeAutoQuality,
+// along with the chosen quality and its SSIM score
+type AutoQualityResult struct {
+	Buffer  []byte
+	Quality int
+	SSIM    float64
+}
+
+// MaxBytesError is returned when the image cannot be encoded
+// within Options.MaxBytes, even using the lowest quality
+type MaxBytesError struct {
+	MaxBytes int
+	Size     int
+}
+
+func (e *MaxBytesError) Error() string {
+	return fmt.Sprintf("Cannot encode the image within %d bytes (smallest encoding is %d bytes)", e.MaxBytes, e.Size)
+}
+
+// encodeToSize returns the highest quality encoding of the image
+// which fits in o.MaxBytes, encoding the same image repeatedly.
+// JPEG and WebP search over the quality, while PNG tries the maximum
+// compression level and then, if supported, palette quantisation.
+// The image is rendered into memory first, so its operations
+// (decode, resize, effects...) only run once.
+func encodeToSize(image *C.VipsImage, o vipsSaveOptions) ([]byte, error) {
+	image, err := vipsCopyMemory(image)
+	if err != nil {
+		return nil, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	buf, err := vipsEncode(image, o)
+	if err != nil || len(buf) <= o.MaxBytes {
+		return buf, err
+	}
+	smallest := len(buf)
+
+	fits := func(o vipsSaveOptions) ([]byte, bool, error) {
+		buf, err := vipsEncode(image, o)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(buf) < smallest {
+			smallest = len(buf)
+		}
+		return buf, len(buf) <= o.MaxBytes, nil
+	}
+
+	if o.Type == PNG {
+		if o.Compression < maxPngCompression {
+			o.Compression = maxPngCompression
+			buf, ok, err := fits(o)
+			if err != nil || ok {
+				return buf, err
+			}
+		}
+		// Palette based PNG is lossy, so fall back to the quality search.
+		// Without palette support, the quality has no effect on the size
+		if !hasPalettePNG {
+			return nil, &MaxBytesError{MaxBytes: o.MaxBytes, Size: smallest}
+		}
+		o.Palette = true
+	}
+
+	if o.Type == WEBP {
+		o.Effort = maxWebpEffort
+	}
+
+	buf, err = searchQuality(minQuality, o.Quality, func(quality int) ([]byte, bool, error) {
+		o.Quality = quality
+		return fits(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if buf == nil {
+		return nil, &MaxBytesError{MaxBytes: o.MaxBytes, Size: smallest}
+	}
+
+	debug("Encode to size: maxBytes=%v, size=%v", o.MaxBytes, len(buf))
+
+	return buf, nil
+}
+
+// encodeAutoQuality performs a binary search over the quality range
+// for the lowest quality whose decoded output reaches the SSIM threshold.
+// If no quality reaches it, the maximum quality is used.
+// Lossless formats are encoded as is.
+// The image is rendered into memory first, so it's processed only once
+// for every encoding and as the SSIM reference.
+func encodeAutoQuality(image *C.VipsImage, o vipsSaveOptions) (AutoQualityResult, error) {
+	if o.Type == PNG {
+		buf, err := vipsEncode(image, o)
+		return AutoQualityResult{Buffer: buf, Quality: o.Quality, SSIM: 1}, err
+	}
+
+	image, err := vipsCopyMemory(image)
+	if err != nil {
+		return AutoQualityResult{}, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	min, max := o.AutoQuality.MinQuality, o.AutoQuality.MaxQuality
+	if min <= 0 {
+		min = autoMinQuality
+	}
+	if max <= 0 || max > 100 {
+		max = autoMaxQuality
+	}
+
+	encode := func(quality int) (AutoQualityResult, error) {
+		o.Quality = quality
+		buf, err := vipsEncode(image, o)
+		if err != nil {
+			return AutoQualityResult{}, err
+		}
+
+		candidate, _, err := vipsRead(buf)
+		if err != nil {
+			return AutoQualityResult{}, err
+		}
+		defer C.g_object_unref(C.gpointer(candidate))
+
+		ssim, err := vipsSSIM(image, candidate)
+		return AutoQualityResult{Buffer: buf, Quality: quality, SSIM: ssim}, err
+	}
+
+	var best AutoQualityResult
+	for low, high := min, max; low <= high; {
+		result, err := encode((low + high) / 2)
+		if err != nil {
+			return AutoQualityResult{}, err
+		}
+
+		if result.SSIM >= o.AutoQuality.SSIM {
+			best = result
+			high = result.Quality - 1
+		} else {
+			low = result.Quality + 1
+		}
+	}
+
+	if best.Buffer == nil {
+		return encode(max)
+	}
+
+	debug("Auto quality: quality=%v, ssim=%v", best.Quality, best.SSIM)
+
+	return best, nil
+}
+
+// searchQuality performs a binary search over the given quality range,
+// returning the encoding of the highest quality accepted by the check function,
+// or nil if no quality is accepted
+func searchQuality(min, max int, check func(quality int) ([]byte, bool, error)) ([]byte, error) {
+	var best []byte
+
+	for min <= max {
+		quality := (min + max) / 2
+
+		buf, ok, err := check(quality)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			best = buf
+			min = quality + 1
+		} else {
+			max = quality - 1
+		}
+	}
+
+	return best, nil
+}
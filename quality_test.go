@@ -0,0 +1,98 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestResizeMaxBytes(t *testing.T) {
+	files := []struct {
+		name     string
+		format   ImageType
+		maxBytes int
+	}{
+		{"test.jpg", JPEG, 20 * 1024},
+		{"test.jpg", WEBP, 10 * 1024},
+	}
+
+	for _, file := range files {
+		options := Options{Width: 800, Height: 600, Type: file.format, MaxBytes: file.maxBytes}
+
+		buf, err := Resize(readImage(file.name), options)
+		if err != nil {
+			t.Fatalf("Resize(imgData, %#v) error: %s", options, err)
+		}
+		if len(buf) > file.maxBytes {
+			t.Errorf("Image exceeds the max bytes: %d > %d", len(buf), file.maxBytes)
+		}
+		if DetermineImageType(buf) != file.format {
+			t.Errorf("Invalid image type")
+		}
+	}
+}
+
+func TestResizeMaxBytesError(t *testing.T) {
+	options := Options{Width: 800, Height: 600, MaxBytes: 100}
+
+	_, err := Resize(readImage("test.jpg"), options)
+	if err == nil {
+		t.Fatal("Expected max bytes error")
+	}
+
+	maxErr, ok := err.(*MaxBytesError)
+	if !ok {
+		t.Fatalf("Invalid error type: %#v", err)
+	}
+	if maxErr.MaxBytes != 100 || maxErr.Size <= 100 {
+		t.Fatalf("Invalid error: %s", maxErr)
+	}
+}
+
+func TestSearchQuality(t *testing.T) {
+	sizes := func(quality int) ([]byte, bool, error) {
+		return []byte{byte(quality)}, quality*100 <= 4250, nil
+	}
+
+	buf, err := searchQuality(1, 100, sizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 1 || buf[0] != 42 {
+		t.Fatalf("Invalid quality: %v", buf)
+	}
+
+	buf, _ = searchQuality(50, 100, sizes)
+	if buf != nil {
+		t.Fatal("Expected no matching quality")
+	}
+}
+
+func TestResizeAutoQuality(t *testing.T) {
+	options := Options{
+		Width:       400,
+		Height:      300,
+		AutoQuality: AutoQuality{SSIM: 0.95, MinQuality: 10, MaxQuality: 90},
+	}
+
+	result, err := ResizeAutoQuality(readImage("test.jpg"), options)
+	if err != nil {
+		t.Fatalf("ResizeAutoQuality(imgData, %#v) error: %s", options, err)
+	}
+
+	if result.Quality < 10 || result.Quality > 90 {
+		t.Errorf("Invalid quality: %d", result.Quality)
+	}
+	if result.Quality < 90 && result.SSIM < 0.95 {
+		t.Errorf("Invalid SSIM score: %f", result.SSIM)
+	}
+	if err := assertSize(result.Buffer, 400, 300); err != nil {
+		t.Error(err)
+	}
+
+	buf, err := Resize(readImage("test.jpg"), options)
+	if err != nil {
+		t.Fatalf("Resize(imgData, %#v) error: %s", options, err)
+	}
+	if len(buf) != len(result.Buffer) {
+		t.Errorf("Resize and ResizeAutoQuality outputs differ: %d != %d", len(buf), len(result.Buffer))
+	}
+}
@@ -8,30 +8,192 @@ import "C"
 
 import (
 	"errors"
+	"fmt"
 	"math"
 )
 
 func Resize(buf []byte, o Options) ([]byte, error) {
 	defer C.vips_thread_shutdown()
 
+	image, o, err := resizeImage(buf, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally get the resultant buffer
+	return vipsSave(image, getSaveOptions(o))
+}
+
+// ResizeAutoQuality processes the image like Resize, encoding it with the
+// lowest quality which reaches the o.AutoQuality SSIM threshold.
+// The threshold defaults to 0.98 if not defined.
+// The chosen quality and its SSIM score are returned along with the image
+func ResizeAutoQuality(buf []byte, o Options) (AutoQualityResult, error) {
+	defer C.vips_thread_shutdown()
+
+	if o.AutoQuality.SSIM == 0 {
+		o.AutoQuality.SSIM = 0.98
+	}
+
+	image, o, err := resizeImage(buf, o)
+	if err != nil {
+		return AutoQualityResult{}, err
+	}
+
+	saveOptions := getSaveOptions(o)
+	image, err = vipsPreSave(image, &saveOptions)
+	if err != nil {
+		return AutoQualityResult{}, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	return encodeAutoQuality(image, saveOptions)
+}
+
+// resizeImage reads and processes the image buffer,
+// returning the image not yet encoded and the options with defaults
+func resizeImage(buf []byte, o Options) (*C.VipsImage, Options, error) {
 	if len(buf) == 0 {
-		return nil, errors.New("Image buffer is empty")
+		return nil, o, errors.New("Image buffer is empty")
 	}
 
 	image, imageType, err := vipsRead(buf)
 	if err != nil {
-		return nil, err
+		return nil, o, err
 	}
 
 	// Define default options
 	applyDefaults(&o, imageType)
 
 	if IsTypeSupported(o.Type) == false {
-		return nil, errors.New("Unsupported image output type")
+		C.g_object_unref(C.gpointer(image))
+		return nil, o, errors.New("Unsupported image output type")
 	}
 
 	debug("Options: %#v", o)
 
+	image, err = processImage(image, buf, imageType, o)
+	if err != nil {
+		return nil, o, err
+	}
+
+	return image, o, nil
+}
+
+// ResizeMulti generates several variants of the same image, one per given
+// options, decoding the input buffer only once.
+// JPEG images are loaded using the largest shrink-on-load level suitable
+// for every variant, then each variant is processed and encoded in turn.
+// Outputs are returned in the same order as the given options.
+func ResizeMulti(buf []byte, o []Options) ([][]byte, error) {
+	defer C.vips_thread_shutdown()
+
+	if len(buf) == 0 {
+		return nil, errors.New("Image buffer is empty")
+	}
+
+	image, imageType, err := vipsRead(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return resizeMultiImage(image, imageType, buf, o)
+}
+
+// resizeMultiImage generates the variants of the image decoded from buf,
+// taking the ownership of the image
+func resizeMultiImage(image *C.VipsImage, imageType ImageType, buf []byte, o []Options) ([][]byte, error) {
+	var err error
+
+	options := make([]Options, len(o))
+	for index, opts := range o {
+		applyDefaults(&opts, imageType)
+		if IsTypeSupported(opts.Type) == false {
+			C.g_object_unref(C.gpointer(image))
+			return nil, errors.New("Unsupported image output type")
+		}
+		options[index] = opts
+	}
+
+	// Try to use libjpeg shrink-on-load
+	if imageType == JPEG {
+		shrink := 0
+		for _, opts := range options {
+			s := calculateOptionsShrink(opts, int(image.Xsize), int(image.Ysize))
+			if shrink == 0 || s < shrink {
+				shrink = s
+			}
+		}
+		if shrink >= 2 {
+			image, _, err = shrinkJpegImage(buf, image, float64(shrink), shrink)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	// Variants are processed one after the other: libvips already spreads
+	// each of them over its thread pool, and its error buffer is global,
+	// so concurrent failures could not be told apart
+	outputs := make([][]byte, len(options))
+	for index := range options {
+		debug("Variant %d options: %#v", index, options[index])
+
+		// Each variant works on its own branch of the decoded image
+		C.g_object_ref(C.gpointer(image))
+		branch, err := vipsCopy(image)
+		if err == nil {
+			branch, err = processImage(branch, nil, imageType, options[index])
+		}
+		if err == nil {
+			outputs[index], err = vipsSave(branch, getSaveOptions(options[index]))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Cannot process variant %d: %s", index, err)
+		}
+	}
+
+	return outputs, nil
+}
+
+// readScaledImage reads the image buffer scaled to the given size,
+// using shrink-on-load if possible. If width or height is zero,
+// it's calculated keeping the aspect ratio.
+// Intended for image analysis, where the output type is irrelevant
+func readScaledImage(buf []byte, width, height int) (*C.VipsImage, error) {
+	if len(buf) == 0 {
+		return nil, errors.New("Image buffer is empty")
+	}
+
+	image, imageType, err := vipsRead(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	o := Options{Width: width, Height: height}
+	applyDefaults(&o, imageType)
+
+	return processImage(image, buf, imageType, o)
+}
+
+// processImage applies the transformations defined by the given options
+// to the image, returning the transformed image not yet encoded.
+// The input buffer is only used to reload JPEG images with shrink-on-load,
+// so a nil buffer can be passed to disable it.
+func processImage(image *C.VipsImage, buf []byte, imageType ImageType, o Options) (*C.VipsImage, error) {
+	var err error
+
+	// Trim the uniform borders first, so the calculations use the trimmed size
+	if o.Trim.Enabled {
+		image, _, err = trimImage(image, o.Trim)
+		if err != nil {
+			return nil, err
+		}
+		// Shrink-on-load would reload the untrimmed image
+		buf = nil
+	}
+
 	inWidth := int(image.Xsize)
 	inHeight := int(image.Ysize)
 
@@ -56,7 +218,7 @@ func Resize(buf []byte, o Options) ([]byte, error) {
 	}
 
 	// Try to use libjpeg shrink-on-load
-	if imageType == JPEG && shrink >= 2 {
+	if imageType == JPEG && shrink >= 2 && len(buf) > 0 {
 		tmpImage, factor, err := shrinkJpegImage(buf, image, factor, shrink)
 		if err != nil {
 			return nil, err
@@ -88,6 +250,12 @@ func Resize(buf []byte, o Options) ([]byte, error) {
 		}
 	}
 
+	// Rotate by an arbitrary angle, if necessary
+	image, err = rotateImage(image, o)
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply effects, if necessary
 	if shouldApplyEffects(o) {
 		image, err = applyEffects(image, o)
@@ -102,22 +270,63 @@ func Resize(buf []byte, o Options) ([]byte, error) {
 		return nil, err
 	}
 
-	saveOptions := vipsSaveOptions{
+	// Add image watermark, if necessary
+	image, err = watermarkWithImage(image, o.WatermarkImage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Composite the layers, if necessary
+	image, err = compositeImage(image, o.Composite)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mask the image, if necessary
+	image, err = maskImage(image, o.Mask, o.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flatten or change the alpha channel, if necessary
+	image, err = alphaImage(image, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// calculateOptionsShrink returns the integral shrink factor
+// required to process an image of the given size with the given options
+func calculateOptionsShrink(o Options, inWidth, inHeight int) int {
+	// The trimmed size is unknown until the image is processed
+	if o.Trim.Enabled {
+		return 1
+	}
+
+	normalizeOperation(&o, inWidth, inHeight)
+	factor := imageCalculations(&o, inWidth, inHeight)
+
+	if !o.Enlarge && !o.Force && inWidth < o.Width && inHeight < o.Height {
+		return 1
+	}
+
+	return calculateShrink(factor, o.Interpolator)
+}
+
+func getSaveOptions(o Options) vipsSaveOptions {
+	return vipsSaveOptions{
 		Quality:        o.Quality,
 		Type:           o.Type,
 		Compression:    o.Compression,
 		Interlace:      o.Interlace,
 		NoProfile:      o.NoProfile,
+		MaxBytes:       o.MaxBytes,
+		AutoQuality:    o.AutoQuality,
 		Interpretation: o.Interpretation,
+		Background:     o.Background,
 	}
-
-	// Finally get the resultant buffer
-	buf, err = vipsSave(image, saveOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	return buf, nil
 }
 
 func applyDefaults(o *Options, imageType ImageType) {
@@ -147,7 +356,8 @@ func shouldTransformImage(o Options, inWidth, inHeight int) bool {
 }
 
 func shouldApplyEffects(o Options) bool {
-	return o.GaussianBlur.Sigma > 0 || o.GaussianBlur.MinAmpl > 0
+	return o.GaussianBlur.Sigma > 0 || o.GaussianBlur.MinAmpl > 0 ||
+		o.Sharpen != (Sharpen{}) || o.Adjust != (Adjust{}) || o.Effects != (Effects{})
 }
 
 func transformImage(image *C.VipsImage, o Options, shrink int, residual float64) (*C.VipsImage, error) {
@@ -193,6 +403,20 @@ func transformImage(image *C.VipsImage, o Options, shrink int, residual float64)
 func applyEffects(image *C.VipsImage, o Options) (*C.VipsImage, error) {
 	var err error
 
+	if o.Adjust != (Adjust{}) {
+		image, err = vipsAdjust(image, o.Adjust)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Effects != (Effects{}) {
+		image, err = vipsEffects(image, o.Effects)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if o.GaussianBlur.Sigma > 0 || o.GaussianBlur.MinAmpl > 0 {
 		image, err = vipsGaussianBlur(image, o.GaussianBlur)
 		if err != nil {
@@ -200,8 +424,90 @@ func applyEffects(image *C.VipsImage, o Options) (*C.VipsImage, error) {
 		}
 	}
 
-	debug("Effects: gaussSigma=%v, gaussMinAmpl=%v",
-		o.GaussianBlur.Sigma, o.GaussianBlur.MinAmpl)
+	if o.Sharpen != (Sharpen{}) {
+		image, err = vipsSharpen(image, o.Sharpen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	debug("Effects: adjust=%#v, effects=%#v, gaussSigma=%v, gaussMinAmpl=%v, sharpen=%#v",
+		o.Adjust, o.Effects, o.GaussianBlur.Sigma, o.GaussianBlur.MinAmpl, o.Sharpen)
+
+	return image, nil
+}
+
+// trimImage removes the uniform borders of the image,
+// returning the area kept along with the trimmed image
+func trimImage(image *C.VipsImage, t Trim) (*C.VipsImage, Area, error) {
+	area, err := vipsFindTrim(image, t)
+	if err != nil {
+		C.g_object_unref(C.gpointer(image))
+		return nil, Area{}, err
+	}
+
+	debug("Trim: %#v", area)
+
+	// Uniform images, or images without borders, are kept untouched
+	if area.Width == 0 || area.Height == 0 ||
+		(area.Width == int(image.Xsize) && area.Height == int(image.Ysize)) {
+		return image, Area{Width: int(image.Xsize), Height: int(image.Ysize)}, nil
+	}
+
+	image, err = vipsExtract(image, area.Left, area.Top, area.Width, area.Height)
+	return image, area, err
+}
+
+func watermarkWithImage(image *C.VipsImage, w WatermarkImage) (*C.VipsImage, error) {
+	if len(w.Buf) == 0 {
+		return image, nil
+	}
+
+	sub, _, err := vipsRead(w.Buf)
+	if err != nil {
+		C.g_object_unref(C.gpointer(image))
+		return nil, err
+	}
+
+	if w.Width > 0 {
+		factor := float64(image.Xsize) * w.Width / float64(sub.Xsize)
+		sub, err = vipsAffineAlpha(sub, factor, factor, BICUBIC)
+		if err != nil {
+			C.g_object_unref(C.gpointer(image))
+			return nil, err
+		}
+	}
+	defer C.g_object_unref(C.gpointer(sub))
+
+	opacity := float64(w.Opacity)
+	if opacity <= 0 || opacity > 1 {
+		opacity = 1
+	}
+
+	left, top := w.Left, w.Top
+	if !w.Tile {
+		left, top = calculatePosition(int(image.Xsize), int(image.Ysize),
+			int(sub.Xsize), int(sub.Ysize), w.Gravity, w.Left, w.Top)
+	}
+
+	debug("Watermark image: %dx%d at %d,%d", sub.Xsize, sub.Ysize, left, top)
+
+	return vipsWatermarkImage(image, sub, left, top, opacity, w.Tile, w.Spacing)
+}
+
+func alphaImage(image *C.VipsImage, o Options) (*C.VipsImage, error) {
+	var err error
+
+	if o.Flatten {
+		image, err = vipsFlatten(image, backgroundColor(o.Background))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Alpha != ALPHA_KEEP {
+		return vipsAlpha(image, o.Alpha)
+	}
 
 	return image, nil
 }
@@ -240,6 +546,23 @@ func extractOrEmbedImage(image *C.VipsImage, o Options) (*C.VipsImage, error) {
 	return image, err
 }
 
+func rotateImage(image *C.VipsImage, o Options) (*C.VipsImage, error) {
+	angle := math.Mod(o.Rotation.Angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	if angle == 0 {
+		return image, nil
+	}
+
+	// Right angles without cropping are lossless and need no background
+	if math.Mod(angle, 90) == 0 && !o.Rotation.Crop {
+		return vipsRotate(image, Angle(angle))
+	}
+
+	return vipsRotateAngle(image, o.Rotation, o.Interpolator)
+}
+
 func rotateAndFlipImage(image *C.VipsImage, o Options) (*C.VipsImage, error) {
 	var err error
 	var direction Direction = -1
@@ -401,6 +724,15 @@ func calculateCrop(inWidth, inHeight, outWidth, outHeight int, gravity Gravity)
 		top = inHeight - outHeight
 	case WEST:
 		top = (inHeight - outHeight + 1) / 2
+	case NORTHEAST:
+		left = inWidth - outWidth
+	case SOUTHEAST:
+		left = inWidth - outWidth
+		top = inHeight - outHeight
+	case SOUTHWEST:
+		top = inHeight - outHeight
+	case NORTHWEST:
+		break
 	default:
 		left = (inWidth - outWidth + 1) / 2
 		top = (inHeight - outHeight + 1) / 2
@@ -409,6 +741,28 @@ func calculateCrop(inWidth, inHeight, outWidth, outHeight int, gravity Gravity)
 	return left, top
 }
 
+// calculatePosition returns the position of an area of the given size placed
+// by gravity in the image, moved inwards from the gravity edges by the given offsets
+func calculatePosition(inWidth, inHeight, width, height int, gravity Gravity, offsetX, offsetY int) (int, int) {
+	left, top := calculateCrop(inWidth, inHeight, width, height, gravity)
+
+	switch gravity {
+	case EAST, NORTHEAST, SOUTHEAST:
+		left -= offsetX
+	default:
+		left += offsetX
+	}
+
+	switch gravity {
+	case SOUTH, SOUTHEAST, SOUTHWEST:
+		top -= offsetY
+	default:
+		top += offsetY
+	}
+
+	return left, top
+}
+
 func calculateRotationAndFlip(image *C.VipsImage, angle Angle) (Angle, bool) {
 	rotate := D0
 	flip := false
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -168,6 +169,82 @@ func TestGaussianBlur(t *testing.T) {
 	Write("fixtures/test_gaussian.jpg", newImg)
 }
 
+func TestSharpen(t *testing.T) {
+	options := Options{Width: 800, Height: 600, Sharpen: Sharpen{Sigma: 1, Amount: 2}}
+	buf, _ := Read("fixtures/test.jpg")
+
+	newImg, err := Resize(buf, options)
+	if err != nil {
+		t.Errorf("Resize(imgData, %#v) error: %#v", options, err)
+	}
+
+	size, _ := Size(newImg)
+	if size.Height != options.Height || size.Width != options.Width {
+		t.Fatalf("Invalid image size: %dx%d", size.Width, size.Height)
+	}
+
+	Write("fixtures/test_sharpen.jpg", newImg)
+}
+
+func TestCalculatePosition(t *testing.T) {
+	tests := []struct {
+		gravity   Gravity
+		left, top int
+	}{
+		{CENTRE, 170, 140},
+		{NORTH, 170, 10},
+		{EAST, 310, 140},
+		{SOUTH, 170, 250},
+		{WEST, 10, 140},
+		{NORTHEAST, 310, 10},
+		{SOUTHEAST, 310, 250},
+		{SOUTHWEST, 10, 250},
+		{NORTHWEST, 10, 10},
+	}
+
+	for _, test := range tests {
+		left, top := calculatePosition(400, 300, 80, 40, test.gravity, 10, 10)
+		if left != test.left || top != test.top {
+			t.Errorf("Invalid position for gravity %d: %d,%d", test.gravity, left, top)
+		}
+	}
+}
+
+func TestWatermarkImage(t *testing.T) {
+	tests := []WatermarkImage{
+		{Buf: readImage("test.png"), Gravity: SOUTHEAST, Left: 20, Top: 20, Width: 0.2, Opacity: 0.6},
+		{Buf: readImage("transparent.png"), Width: 0.1, Tile: true, Spacing: 40},
+	}
+
+	for _, w := range tests {
+		buf, err := Resize(readImage("test.jpg"), Options{Width: 800, Height: 600, WatermarkImage: w})
+		if err != nil {
+			t.Fatalf("Cannot add the watermark: %s", err)
+		}
+		if err := assertSize(buf, 800, 600); err != nil {
+			t.Error(err)
+		}
+		if DetermineImageType(buf) != JPEG {
+			t.Fatal("Image is not jpeg")
+		}
+	}
+
+	// Opaque images must stay opaque
+	buf, err := Resize(readImage("test.jpg"), Options{Type: PNG, WatermarkImage: tests[0]})
+	if err != nil {
+		t.Fatalf("Cannot add the watermark: %s", err)
+	}
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if metadata.Alpha {
+		t.Error("Watermarked image must not have an alpha channel")
+	}
+
+	Write("fixtures/test_watermark_image_out.png", buf)
+}
+
 func TestConvert(t *testing.T) {
 	width, height := 300, 240
 	formats := [3]ImageType{PNG, WEBP, JPEG}
@@ -242,6 +319,71 @@ func TestResizePngWithTransparency(t *testing.T) {
 	Write("fixtures/transparent_out.png", newImg)
 }
 
+func TestResizeMulti(t *testing.T) {
+	options := []Options{
+		{Width: 800, Height: 600, Crop: true},
+		{Width: 400, Height: 300, Crop: true, Type: WEBP},
+		{Width: 200, Height: 150, Crop: true, Type: PNG},
+		{Width: 100},
+	}
+
+	outputs, err := ResizeMulti(readImage("test.jpg"), options)
+	if err != nil {
+		t.Fatalf("ResizeMulti(imgData, %#v) error: %s", options, err)
+	}
+	if len(outputs) != len(options) {
+		t.Fatalf("Invalid number of outputs: %d", len(outputs))
+	}
+
+	expected := []struct {
+		width, height int
+		format        ImageType
+	}{
+		{800, 600, JPEG},
+		{400, 300, WEBP},
+		{200, 150, PNG},
+		{100, 62, JPEG},
+	}
+
+	for index, buf := range outputs {
+		if DetermineImageType(buf) != expected[index].format {
+			t.Errorf("Invalid image type for variant %d", index)
+		}
+		if err := assertSize(buf, expected[index].width, expected[index].height); err != nil {
+			t.Errorf("Variant %d: %s", index, err)
+		}
+	}
+}
+
+func TestResizeMultiVariantError(t *testing.T) {
+	options := []Options{
+		{Width: 400, Height: 300, Crop: true},
+		// Area out of the image bounds
+		{Top: 5000, Left: 5000, AreaWidth: 100, AreaHeight: 100},
+		{Width: 200, Height: 150, Crop: true},
+	}
+
+	_, err := ResizeMulti(readImage("test.jpg"), options)
+	if err == nil {
+		t.Fatal("Expected variant error")
+	}
+	if !strings.HasPrefix(err.Error(), "Cannot process variant 1: ") || !strings.Contains(err.Error(), "extract_area") {
+		t.Errorf("Error not attributed to the failing variant: %s", err)
+	}
+
+	// The error buffer is cleared for the next operations
+	if _, err := ResizeMulti(readImage("test.jpg"), []Options{options[0], options[2]}); err != nil {
+		t.Errorf("Cannot process the variants: %s", err)
+	}
+}
+
+func TestResizeMultiUnsupportedType(t *testing.T) {
+	_, err := ResizeMulti(readImage("test.jpg"), []Options{{Width: 100, Type: TIFF}})
+	if err == nil {
+		t.Fatal("Expected unsupported type error")
+	}
+}
+
 func runBenchmarkResize(file string, o Options, b *testing.B) {
 	buf, _ := Read(path.Join("fixtures", file))
 
@@ -250,6 +392,19 @@ func runBenchmarkResize(file string, o Options, b *testing.B) {
 	}
 }
 
+func BenchmarkResizeMultiJpeg(b *testing.B) {
+	buf, _ := Read("fixtures/test.jpg")
+	options := []Options{
+		{Width: 800, Height: 600},
+		{Width: 400, Height: 300},
+		{Width: 200, Height: 150, Type: WEBP},
+	}
+
+	for n := 0; n < b.N; n++ {
+		ResizeMulti(buf, options)
+	}
+}
+
 func BenchmarkRotateJpeg(b *testing.B) {
 	options := Options{Rotate: 180}
 	runBenchmarkResize("test.jpg", options, b)
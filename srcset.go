@@ -0,0 +1,222 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SrcsetOptions defines the responsive variants generated by Srcset.
+// Target widths are given in CSS pixels and multiplied by every density.
+type SrcsetOptions struct {
+	// Explicit list of target widths
+	Widths []int
+	// Range of target widths, used when Widths is empty
+	MinWidth int
+	MaxWidth int
+	Step     int
+	// Pixel density multipliers, 1x by default
+	Densities []float64
+	// Output formats, the source image type by default.
+	// The last type is used as the <img> fallback by Picture()
+	Types []ImageType
+	// Base options applied to every variant (quality, crop, enlarge...).
+	// If both Width and Height are defined, their ratio is kept by every variant
+	Options Options
+}
+
+// SrcsetVariant describes a generated variant
+type SrcsetVariant struct {
+	Width   int
+	Height  int
+	Density float64
+	Size    int
+	Type    ImageType
+	Buffer  []byte
+}
+
+// SrcsetResult holds the generated variants, ordered by type and width
+type SrcsetResult struct {
+	Variants []SrcsetVariant
+	types    []ImageType
+}
+
+// Srcset generates the responsive variants of the given image.
+// Variants never exceed the source width unless Options.Enlarge is defined,
+// and widths collapsing to the same size are only generated once.
+func Srcset(buf []byte, o SrcsetOptions) (SrcsetResult, error) {
+	defer C.vips_thread_shutdown()
+
+	widths, err := srcsetWidths(o)
+	if err != nil {
+		return SrcsetResult{}, err
+	}
+
+	if len(buf) == 0 {
+		return SrcsetResult{}, errors.New("Image buffer is empty")
+	}
+
+	// The source is decoded once, for its size and for every variant
+	image, imageType, err := vipsRead(buf)
+	if err != nil {
+		return SrcsetResult{}, err
+	}
+
+	srcWidth := int(image.Xsize)
+	if vipsExifOrientation(image) >= 5 && !o.Options.NoAutoRotate {
+		srcWidth = int(image.Ysize)
+	}
+
+	densities := o.Densities
+	if len(densities) == 0 {
+		densities = []float64{1}
+	}
+
+	types := o.Types
+	if len(types) == 0 {
+		types = []ImageType{imageType}
+	}
+
+	var variants []SrcsetVariant
+	var options []Options
+	for _, t := range types {
+		seen := make(map[int]bool)
+		for _, width := range widths {
+			for _, density := range densities {
+				pixels := int(math.Floor(float64(width)*density + 0.5))
+				if pixels > srcWidth && !o.Options.Enlarge {
+					pixels = srcWidth
+				}
+				if pixels <= 0 || seen[pixels] {
+					continue
+				}
+				seen[pixels] = true
+
+				opts := o.Options
+				opts.Type = t
+				opts.Width = pixels
+				opts.Height = 0
+				if o.Options.Width > 0 && o.Options.Height > 0 {
+					opts.Height = int(math.Floor(float64(pixels*o.Options.Height)/float64(o.Options.Width) + 0.5))
+				}
+
+				options = append(options, opts)
+				variants = append(variants, SrcsetVariant{Width: pixels, Density: density, Type: t})
+			}
+		}
+	}
+
+	outputs, err := resizeMultiImage(image, imageType, buf, options)
+	if err != nil {
+		return SrcsetResult{}, err
+	}
+
+	for index, out := range outputs {
+		size, err := Size(out)
+		if err != nil {
+			return SrcsetResult{}, err
+		}
+		variants[index].Width = size.Width
+		variants[index].Height = size.Height
+		variants[index].Size = len(out)
+		variants[index].Buffer = out
+	}
+
+	sortSrcsetVariants(variants, types)
+
+	return SrcsetResult{Variants: variants, types: types}, nil
+}
+
+// Srcset returns the srcset attribute value for the variants of the given type,
+// using width descriptors. The url function maps every variant to its public URL
+func (r SrcsetResult) Srcset(t ImageType, url func(SrcsetVariant) string) string {
+	var candidates []string
+	for _, variant := range r.Variants {
+		if variant.Type == t {
+			candidates = append(candidates, fmt.Sprintf("%s %dw", url(variant), variant.Width))
+		}
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// Picture returns a <picture> element with a <source> per output type
+// and an <img> fallback using the last output type
+func (r SrcsetResult) Picture(url func(SrcsetVariant) string, sizes, alt string) string {
+	if len(r.types) == 0 {
+		return ""
+	}
+
+	var b bytes.Buffer
+	fallback := r.types[len(r.types)-1]
+
+	b.WriteString("<picture>")
+	for _, t := range r.types[:len(r.types)-1] {
+		fmt.Fprintf(&b, `<source type="%s" srcset="%s" sizes="%s">`,
+			getImageMimeType(t), html.EscapeString(r.Srcset(t, url)), html.EscapeString(sizes))
+	}
+
+	src := ""
+	for _, variant := range r.Variants {
+		if variant.Type == fallback {
+			src = url(variant)
+			break
+		}
+	}
+
+	fmt.Fprintf(&b, `<img src="%s" srcset="%s" sizes="%s" alt="%s">`,
+		html.EscapeString(src), html.EscapeString(r.Srcset(fallback, url)),
+		html.EscapeString(sizes), html.EscapeString(alt))
+	b.WriteString("</picture>")
+
+	return b.String()
+}
+
+func srcsetWidths(o SrcsetOptions) ([]int, error) {
+	if len(o.Widths) > 0 {
+		return o.Widths, nil
+	}
+
+	if o.MinWidth <= 0 || o.MaxWidth < o.MinWidth || o.Step <= 0 {
+		return nil, errors.New("Srcset widths or a valid min/max/step range are required")
+	}
+
+	var widths []int
+	for width := o.MinWidth; width < o.MaxWidth; width += o.Step {
+		widths = append(widths, width)
+	}
+	return append(widths, o.MaxWidth), nil
+}
+
+func sortSrcsetVariants(variants []SrcsetVariant, types []ImageType) {
+	order := make(map[ImageType]int)
+	for index, t := range types {
+		order[t] = index
+	}
+
+	sort.Sort(srcsetVariants{variants, order})
+}
+
+type srcsetVariants struct {
+	variants []SrcsetVariant
+	order    map[ImageType]int
+}
+
+func (v srcsetVariants) Len() int      { return len(v.variants) }
+func (v srcsetVariants) Swap(i, j int) { v.variants[i], v.variants[j] = v.variants[j], v.variants[i] }
+func (v srcsetVariants) Less(i, j int) bool {
+	a, b := v.variants[i], v.variants[j]
+	if a.Type != b.Type {
+		return v.order[a.Type] < v.order[b.Type]
+	}
+	return a.Width < b.Width
+}
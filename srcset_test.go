@@ -0,0 +1,91 @@
+package bimg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSrcset(t *testing.T) {
+	result, err := Srcset(readImage("test.jpg"), SrcsetOptions{
+		Widths:    []int{200, 400, 4000},
+		Densities: []float64{1, 2},
+		Types:     []ImageType{WEBP, JPEG},
+	})
+	if err != nil {
+		t.Fatalf("Cannot generate the srcset: %s", err)
+	}
+
+	expected := []int{200, 400, 800, 1680}
+	if len(result.Variants) != len(expected)*2 {
+		t.Fatalf("Invalid number of variants: %d", len(result.Variants))
+	}
+
+	for index, variant := range result.Variants {
+		format := WEBP
+		if index >= len(expected) {
+			format = JPEG
+		}
+		if variant.Type != format {
+			t.Errorf("Invalid variant type: %d", variant.Type)
+		}
+		if variant.Width != expected[index%len(expected)] {
+			t.Errorf("Invalid variant width: %d", variant.Width)
+		}
+		if variant.Size != len(variant.Buffer) || DetermineImageType(variant.Buffer) != format {
+			t.Errorf("Invalid variant buffer")
+		}
+	}
+
+	url := func(v SrcsetVariant) string {
+		return fmt.Sprintf("/img-%d.%s", v.Width, getImageTypeName(v.Type))
+	}
+
+	srcset := result.Srcset(JPEG, url)
+	if srcset != "/img-200.jpeg 200w, /img-400.jpeg 400w, /img-800.jpeg 800w, /img-1680.jpeg 1680w" {
+		t.Errorf("Invalid srcset: %s", srcset)
+	}
+
+	picture := result.Picture(url, "100vw", "Test")
+	if !strings.HasPrefix(picture, `<picture><source type="image/webp"`) ||
+		!strings.Contains(picture, `<img src="/img-200.jpeg"`) {
+		t.Errorf("Invalid picture: %s", picture)
+	}
+}
+
+func TestSrcsetWidthsRange(t *testing.T) {
+	widths, err := srcsetWidths(SrcsetOptions{MinWidth: 100, MaxWidth: 350, Step: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(widths) != "[100 200 300 350]" {
+		t.Fatalf("Invalid widths: %v", widths)
+	}
+
+	_, err = srcsetWidths(SrcsetOptions{MinWidth: 100})
+	if err == nil {
+		t.Fatal("Expected invalid range error")
+	}
+}
+
+func TestSortSrcsetVariants(t *testing.T) {
+	variants := []SrcsetVariant{
+		{Width: 800, Type: JPEG},
+		{Width: 400, Type: WEBP},
+		{Width: 200, Type: JPEG},
+		{Width: 800, Type: WEBP},
+	}
+	sortSrcsetVariants(variants, []ImageType{WEBP, JPEG})
+
+	expected := []SrcsetVariant{
+		{Width: 400, Type: WEBP},
+		{Width: 800, Type: WEBP},
+		{Width: 200, Type: JPEG},
+		{Width: 800, Type: JPEG},
+	}
+	for index, variant := range variants {
+		if variant.Width != expected[index].Width || variant.Type != expected[index].Type {
+			t.Errorf("Invalid variant %d: %#v", index, variant)
+		}
+	}
+}
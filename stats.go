@@ -0,0 +1,178 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+)
+
+// Number of statistics computed by vips_stats for every band:
+// min, max, sum, sum of squares, mean, deviation, xmin, ymin, xmax, ymax
+const statsColumns = 10
+
+// Largest difference between the RGB bands of a pixel considered grey,
+// absorbing the rounding errors of the JPEG colour conversion
+const greyscaleTolerance = 2
+
+// BandStats holds the statistics of an image band, in pixel values
+type BandStats struct {
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+}
+
+// ImageStats holds the statistics of every image band.
+// If Alpha is true, the last band is the alpha channel
+type ImageStats struct {
+	Bands []BandStats
+	Alpha bool
+}
+
+// ImageHistogram holds the histogram of every image band,
+// with a bin per possible value (256 for 8 bits images, 65536 for 16 bits)
+type ImageHistogram struct {
+	Bands [][]uint64
+	Alpha bool
+}
+
+// Stats returns the min, max, mean and standard deviation of every image band
+func Stats(buf []byte) (ImageStats, error) {
+	defer C.vips_thread_shutdown()
+
+	image, _, err := vipsRead(buf)
+	if err != nil {
+		return ImageStats{}, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	values, err := vipsStats(image)
+	if err != nil {
+		return ImageStats{}, err
+	}
+
+	bands := int(image.Bands)
+	if len(values) != (bands+1)*statsColumns {
+		return ImageStats{}, errors.New("Invalid image statistics")
+	}
+
+	stats := ImageStats{Bands: make([]BandStats, bands), Alpha: vipsHasAlpha(image)}
+	for band := range stats.Bands {
+		// The first row holds the statistics of all bands together
+		row := values[(band+1)*statsColumns:]
+		stats.Bands[band] = BandStats{Min: row[0], Max: row[1], Mean: row[4], StdDev: row[5]}
+	}
+
+	return stats, nil
+}
+
+// Histogram returns the number of pixels of every value, per image band
+func Histogram(buf []byte) (ImageHistogram, error) {
+	defer C.vips_thread_shutdown()
+
+	image, _, err := vipsRead(buf)
+	if err != nil {
+		return ImageHistogram{}, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	values, err := vipsHistogram(image)
+	if err != nil {
+		return ImageHistogram{}, err
+	}
+
+	// The histogram has a pixel per possible value, with the image bands
+	bands := int(image.Bands)
+	bins := len(values) / bands
+	if bins == 0 || len(values) != bins*bands {
+		return ImageHistogram{}, errors.New("Invalid image histogram")
+	}
+
+	histogram := ImageHistogram{Bands: make([][]uint64, bands), Alpha: vipsHasAlpha(image)}
+	for band := range histogram.Bands {
+		histogram.Bands[band] = make([]uint64, bins)
+		for bin := range histogram.Bands[band] {
+			histogram.Bands[band][bin] = uint64(values[bin*bands+band])
+		}
+	}
+
+	return histogram, nil
+}
+
+// IsBlank reports whether the image is uniform, such as an empty scanned page:
+// the standard deviation of every colour band must not exceed the threshold,
+// in pixel values. The alpha channel is ignored
+func IsBlank(buf []byte, threshold float64) (bool, error) {
+	stats, err := Stats(buf)
+	if err != nil {
+		return false, err
+	}
+
+	bands := stats.Bands
+	if stats.Alpha {
+		bands = bands[:len(bands)-1]
+	}
+
+	for _, band := range bands {
+		if band.StdDev > threshold {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// IsGreyscale reports whether the image only contains grey pixels,
+// either because of its colourspace or because its RGB bands are equal
+func IsGreyscale(buf []byte) (bool, error) {
+	defer C.vips_thread_shutdown()
+
+	image, _, err := vipsRead(buf)
+	if err != nil {
+		return false, err
+	}
+
+	interpretation := Interpretation(image.Type)
+	bands := int(image.Bands)
+	if bands < 3 || interpretation == INTERPRETATION_B_W || interpretation == INTERPRETATION_GREY16 {
+		C.g_object_unref(C.gpointer(image))
+		return true, nil
+	}
+
+	image, err = vipsSRGB(image)
+	if err != nil {
+		return false, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	diff, err := vipsBandDifference(image)
+	if err != nil {
+		return false, err
+	}
+
+	return diff <= greyscaleTolerance, nil
+}
+
+// Get the statistics of every image band
+func (i *Image) Stats() (ImageStats, error) {
+	return Stats(i.buffer)
+}
+
+// Get the histogram of every image band
+func (i *Image) Histogram() (ImageHistogram, error) {
+	return Histogram(i.buffer)
+}
+
+// Check if the image is uniform, up to the given standard deviation
+func (i *Image) IsBlank(threshold float64) (bool, error) {
+	return IsBlank(i.buffer, threshold)
+}
+
+// Check if the image only contains grey pixels
+func (i *Image) IsGreyscale() (bool, error) {
+	return IsGreyscale(i.buffer)
+}
@@ -0,0 +1,97 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	stats, err := initImage("test.png").Stats()
+	if err != nil {
+		t.Fatalf("Cannot get the image statistics: %s", err)
+	}
+
+	if len(stats.Bands) != 4 || !stats.Alpha {
+		t.Fatalf("Invalid number of bands: %d", len(stats.Bands))
+	}
+
+	for _, band := range stats.Bands {
+		if band.Min < 0 || band.Max > 255 || band.Min > band.Mean || band.Mean > band.Max || band.StdDev < 0 {
+			t.Errorf("Invalid band statistics: %#v", band)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	histogram, err := initImage("test.jpg").Histogram()
+	if err != nil {
+		t.Fatalf("Cannot get the image histogram: %s", err)
+	}
+
+	if len(histogram.Bands) != 3 || histogram.Alpha {
+		t.Fatalf("Invalid number of bands: %d", len(histogram.Bands))
+	}
+
+	for _, bins := range histogram.Bands {
+		if len(bins) != 256 {
+			t.Fatalf("Invalid number of bins: %d", len(bins))
+		}
+
+		total := uint64(0)
+		for _, count := range bins {
+			total += count
+		}
+		if total != 1680*1050 {
+			t.Errorf("Invalid number of pixels: %d", total)
+		}
+	}
+}
+
+func TestIsBlank(t *testing.T) {
+	blank, err := initImage("test.jpg").IsBlank(1)
+	if err != nil {
+		t.Fatalf("Cannot check the image: %s", err)
+	}
+	if blank {
+		t.Error("Image must not be blank")
+	}
+
+	rgba := make([]byte, 4*4*4)
+	for i := range rgba {
+		rgba[i] = 250
+	}
+	buf, err := DecodeBlurHash(encodeBlurHash(rgba, 4, 4, 1, 1), 64, 64, 1)
+	if err != nil {
+		t.Fatalf("Cannot create a uniform image: %s", err)
+	}
+
+	blank, err = IsBlank(buf, 1)
+	if err != nil {
+		t.Fatalf("Cannot check the image: %s", err)
+	}
+	if !blank {
+		t.Error("Uniform image must be blank")
+	}
+}
+
+func TestIsGreyscale(t *testing.T) {
+	grey, err := initImage("test.jpg").IsGreyscale()
+	if err != nil {
+		t.Fatalf("Cannot check the image: %s", err)
+	}
+	if grey {
+		t.Error("Colour image must not be greyscale")
+	}
+
+	buf, err := initImage("test.jpg").Colourspace(INTERPRETATION_B_W)
+	if err != nil {
+		t.Fatalf("Cannot convert the image: %s", err)
+	}
+
+	grey, err = IsGreyscale(buf)
+	if err != nil {
+		t.Fatalf("Cannot check the image: %s", err)
+	}
+	if !grey {
+		t.Error("Black and white image must be greyscale")
+	}
+}
@@ -0,0 +1,84 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"html"
+	"strconv"
+)
+
+// TextOptions defines how RenderText draws a text as an image
+type TextOptions struct {
+	Text string
+	// Interpret the text as Pango markup, such as <b>bold</b>.
+	// Otherwise the text is escaped and drawn as is
+	Markup bool
+	// Pango font description, "sans" by default
+	Font string
+	// Path of a font file to load, such as a TTF, whose family is selected with Font.
+	// Requires libvips 8.9+
+	FontFile string
+	// Font size in points, added to the Font description if defined
+	Size int
+	// Resolution of the text, 72 by default
+	DPI int
+	// Wrap the text to this width in pixels. No wrapping if zero
+	Width int
+	Align Align
+	// Justify the lines of wrapped text. Requires libvips 8.8+
+	Justify bool
+	// Extra space between the text lines in points. Requires libvips 8.8+
+	LineSpacing int
+	// Text colour, black by default
+	Color Color
+	// Background colour, white if nil
+	Background *Color
+	// Draw the text over a transparent background instead
+	Transparent bool
+	// Space in pixels around the text
+	Padding int
+	// Output type, PNG by default
+	Type    ImageType
+	Quality int
+}
+
+// RenderText creates an image sized to fit the given text,
+// for example to generate badges or the initials of an avatar
+func RenderText(o TextOptions) ([]byte, error) {
+	defer C.vips_thread_shutdown()
+
+	if o.Text == "" {
+		return nil, errors.New("Text is empty")
+	}
+
+	if !o.Markup {
+		o.Text = html.EscapeString(o.Text)
+	}
+	if o.Font == "" {
+		o.Font = "sans"
+	}
+	if o.Size > 0 {
+		o.Font += " " + strconv.Itoa(o.Size)
+	}
+	if o.DPI == 0 {
+		o.DPI = 72
+	}
+	if o.Type == UNKNOWN {
+		o.Type = PNG
+	}
+	if o.Quality == 0 {
+		o.Quality = QUALITY
+	}
+
+	image, err := vipsRenderText(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return vipsSave(image, vipsSaveOptions{Type: o.Type, Quality: o.Quality, Compression: 6})
+}
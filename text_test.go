@@ -0,0 +1,89 @@
+package bimg
+
+import (
+	"testing"
+)
+
+func TestRenderText(t *testing.T) {
+	o := TextOptions{Text: "Hello & <world>", Size: 24, Color: Color{255, 0, 0}}
+
+	buf, err := RenderText(o)
+	if err != nil {
+		t.Fatalf("Cannot render the text: %s", err)
+	}
+	if DetermineImageType(buf) != PNG {
+		t.Fatal("Image is not png")
+	}
+	size, err := Size(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image size: %s", err)
+	}
+	if size.Width <= size.Height {
+		t.Errorf("Invalid text size: %dx%d", size.Width, size.Height)
+	}
+
+	o.Padding = 10
+	padded, err := RenderText(o)
+	if err != nil {
+		t.Fatalf("Cannot render the text: %s", err)
+	}
+	if err := assertSize(padded, size.Width+20, size.Height+20); err != nil {
+		t.Error(err)
+	}
+
+	Write("fixtures/test_text_out.png", padded)
+}
+
+func TestRenderTextTransparent(t *testing.T) {
+	buf, err := RenderText(TextOptions{
+		Text:        "<b>AB</b>\nCD",
+		Markup:      true,
+		Size:        48,
+		Align:       ALIGN_CENTRE,
+		Color:       Color{255, 255, 255},
+		Transparent: true,
+	})
+	if err != nil {
+		t.Fatalf("Cannot render the text: %s", err)
+	}
+
+	metadata, err := Metadata(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image metadata: %s", err)
+	}
+	if !metadata.Alpha {
+		t.Error("Transparent text must have an alpha channel")
+	}
+
+	Write("fixtures/test_text_transparent_out.png", buf)
+}
+
+func TestRenderTextWhiteOnBlack(t *testing.T) {
+	buf, err := RenderText(TextOptions{
+		Text:       "Badge",
+		Size:       24,
+		Padding:    10,
+		Color:      Color{255, 255, 255},
+		Background: &Color{},
+	})
+	if err != nil {
+		t.Fatalf("Cannot render the text: %s", err)
+	}
+
+	stats, err := Stats(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the image stats: %s", err)
+	}
+	// Mostly black background with white text
+	for _, band := range stats.Bands {
+		if band.Min > 5 || band.Max < 250 || band.Mean > 128 {
+			t.Errorf("Text must be white on black: %#v", band)
+		}
+	}
+}
+
+func TestRenderTextEmpty(t *testing.T) {
+	if _, err := RenderText(TextOptions{}); err == nil {
+		t.Error("Empty text must fail")
+	}
+}
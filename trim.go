@@ -0,0 +1,81 @@
+package bimg
+
+/*
+#cgo pkg-config: vips
+#include "vips/vips.h"
+*/
+import "C"
+
+import (
+	"errors"
+)
+
+// FindTrim returns the area of the image remaining once its uniform
+// borders are trimmed, in the coordinates of the stored image
+// (before any EXIF based rotation). The whole image area is returned
+// if it has no borders or if it's uniform.
+// Requires libvips 8.6+
+func FindTrim(buf []byte, t Trim) (Area, error) {
+	defer C.vips_thread_shutdown()
+
+	image, _, err := vipsRead(buf)
+	if err != nil {
+		return Area{}, err
+	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	area, err := vipsFindTrim(image, t)
+	if err != nil {
+		return Area{}, err
+	}
+
+	if area.Width == 0 || area.Height == 0 {
+		area = Area{Width: int(image.Xsize), Height: int(image.Ysize)}
+	}
+
+	return area, nil
+}
+
+// ResizeTrim processes the image like Resize with o.Trim enabled,
+// returning the area kept by the trim along with the image,
+// in the coordinates of the stored image. The whole image area
+// is returned if nothing was trimmed.
+// Requires libvips 8.6+
+func ResizeTrim(buf []byte, o Options) ([]byte, Area, error) {
+	defer C.vips_thread_shutdown()
+
+	if len(buf) == 0 {
+		return nil, Area{}, errors.New("Image buffer is empty")
+	}
+
+	image, imageType, err := vipsRead(buf)
+	if err != nil {
+		return nil, Area{}, err
+	}
+
+	applyDefaults(&o, imageType)
+
+	if IsTypeSupported(o.Type) == false {
+		C.g_object_unref(C.gpointer(image))
+		return nil, Area{}, errors.New("Unsupported image output type")
+	}
+
+	image, area, err := trimImage(image, o.Trim)
+	if err != nil {
+		return nil, Area{}, err
+	}
+
+	// The image is already trimmed, and shrink-on-load would reload it untrimmed
+	o.Trim.Enabled = false
+	image, err = processImage(image, nil, imageType, o)
+	if err != nil {
+		return nil, Area{}, err
+	}
+
+	out, err := vipsSave(image, getSaveOptions(o))
+	if err != nil {
+		return nil, Area{}, err
+	}
+
+	return out, area, nil
+}
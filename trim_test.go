@@ -0,0 +1,92 @@
+package bimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestTrim(t *testing.T) {
+	// Embed the image in a black square, adding horizontal borders
+	buf, err := Resize(readImage("test.jpg"), Options{Width: 400, Height: 400, Embed: true, Type: PNG})
+	if err != nil {
+		t.Fatalf("Cannot embed the image: %s", err)
+	}
+
+	area, err := FindTrim(buf, Trim{})
+	if err != nil {
+		t.Fatalf("Cannot find the trim area: %s", err)
+	}
+	if area.Left != 0 || area.Width != 400 || area.Top <= 0 || area.Height >= 400 {
+		t.Fatalf("Invalid trim area: %#v", area)
+	}
+
+	trimmed, err := NewImage(buf).Trim(Trim{AutoBackground: true})
+	if err != nil {
+		t.Fatalf("Cannot trim the image: %s", err)
+	}
+	if err := assertSize(trimmed, area.Width, area.Height); err != nil {
+		t.Error(err)
+	}
+
+	// Resize calculations use the trimmed size
+	resized, applied, err := ResizeTrim(buf, Options{Width: 200, Trim: Trim{Enabled: true}})
+	if err != nil {
+		t.Fatalf("Cannot trim the image: %s", err)
+	}
+	if err := assertSize(resized, 200, area.Height/2); err != nil {
+		t.Error(err)
+	}
+	if applied != area {
+		t.Errorf("Invalid applied trim area: %#v != %#v", applied, area)
+	}
+
+	Write("fixtures/test_trim_out.png", trimmed)
+}
+
+func TestTrimUniformImage(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 50, 40)))
+
+	area, err := FindTrim(buf.Bytes(), Trim{})
+	if err != nil {
+		t.Fatalf("Cannot find the trim area: %s", err)
+	}
+	if area != (Area{Width: 50, Height: 40}) {
+		t.Fatalf("Invalid trim area: %#v", area)
+	}
+
+	_, applied, err := ResizeTrim(buf.Bytes(), Options{Trim: Trim{Enabled: true}})
+	if err != nil {
+		t.Fatalf("Cannot trim the image: %s", err)
+	}
+	if applied != area {
+		t.Errorf("Invalid applied trim area: %#v != %#v", applied, area)
+	}
+}
+
+func TestTrim16Bits(t *testing.T) {
+	// White borders around a black square, in a 16 bits PNG
+	page := image.NewGray16(image.Rect(0, 0, 60, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 60; x++ {
+			c := uint16(65535)
+			if x >= 20 && x < 40 && y >= 10 && y < 30 {
+				c = 0
+			}
+			page.SetGray16(x, y, color.Gray16{c})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, page)
+
+	area, err := FindTrim(buf.Bytes(), Trim{Background: Color{255, 255, 255}})
+	if err != nil {
+		t.Fatalf("Cannot find the trim area: %s", err)
+	}
+	if area != (Area{Left: 20, Top: 10, Width: 20, Height: 20}) {
+		t.Fatalf("Invalid trim area: %#v", area)
+	}
+}
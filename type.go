@@ -26,6 +26,11 @@ func IsTypeSupported(t ImageType) bool {
 	return t == JPEG || t == PNG || t == WEBP || t == MAGICK
 }
 
+// imageTypeHasAlpha reports whether the output type can store an alpha channel
+func imageTypeHasAlpha(t ImageType) bool {
+	return t != JPEG
+}
+
 // Check if a given image type name is supported
 func IsTypeNameSupported(t string) bool {
 	return t == "jpeg" ||
@@ -35,6 +40,20 @@ func IsTypeNameSupported(t string) bool {
 		t == "magick"
 }
 
+func getImageMimeType(code ImageType) string {
+	switch code {
+	case JPEG:
+		return "image/jpeg"
+	case WEBP:
+		return "image/webp"
+	case PNG:
+		return "image/png"
+	case TIFF:
+		return "image/tiff"
+	}
+	return "application/octet-stream"
+}
+
 func getImageTypeName(code ImageType) string {
 	imageType := "unknown"
 
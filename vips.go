@@ -8,6 +8,7 @@ import "C"
 
 import (
 	"errors"
+	"math"
 	"os"
 	"runtime"
 	"strings"
@@ -39,7 +40,19 @@ type vipsSaveOptions struct {
 	Type           ImageType
 	Interlace      bool
 	NoProfile      bool
+	Palette        bool
+	Effort         int
+	MaxBytes       int
+	AutoQuality    AutoQuality
 	Interpretation Interpretation
+	Background     *Color
+}
+
+type vipsCompareResult struct {
+	Max    float64
+	Mean   float64
+	MSE    float64
+	Pixels int
 }
 
 type vipsWatermarkOptions struct {
@@ -49,11 +62,33 @@ type vipsWatermarkOptions struct {
 	NoReplicate C.int
 	Opacity     C.float
 	Background  [3]C.double
+	Left        C.int
+	Top         C.int
+	Align       C.int
+	Spacing     C.int
+	Rotate      C.int
+	Matrix      [4]C.double
 }
 
 type vipsWatermarkTextOptions struct {
-	Text *C.char
-	Font *C.char
+	Text     *C.char
+	Font     *C.char
+	FontFile *C.char
+}
+
+type vipsRenderTextOptions struct {
+	Text        *C.char
+	Font        *C.char
+	FontFile    *C.char
+	Width       C.int
+	DPI         C.int
+	Align       C.int
+	Justify     C.int
+	Spacing     C.int
+	Padding     C.int
+	Transparent C.int
+	Foreground  [3]C.double
+	Background  [3]C.double
 }
 
 func init() {
@@ -148,7 +183,27 @@ func vipsRotate(image *C.VipsImage, angle Angle) (*C.VipsImage, error) {
 	var out *C.VipsImage
 	defer C.g_object_unref(C.gpointer(image))
 
-	err := C.vips_rotate(image, &out, C.int(angle))
+	err := C.vips_rotate_bridge(image, &out, C.int(angle))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsRotateAngle(image *C.VipsImage, r Rotation, i Interpolator) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	cstring := C.CString(i.String())
+	interpolator := C.vips_interpolate_new(cstring)
+
+	defer C.free(unsafe.Pointer(cstring))
+	defer C.g_object_unref(C.gpointer(image))
+	defer C.g_object_unref(C.gpointer(interpolator))
+
+	matrix := rotationMatrix(r.Angle)
+	err := C.vips_rotate_matrix_bridge(image, &out, &matrix[0],
+		C.double(r.Background.R), C.double(r.Background.G), C.double(r.Background.B),
+		C.int(boolToInt(r.Transparent)), C.int(boolToInt(r.Crop)), interpolator)
 	if err != 0 {
 		return nil, catchVipsError()
 	}
@@ -156,6 +211,16 @@ func vipsRotate(image *C.VipsImage, angle Angle) (*C.VipsImage, error) {
 	return out, nil
 }
 
+// rotationMatrix returns the affine matrix of a clockwise rotation
+// by the given angle in degrees, as the Y axis points down
+func rotationMatrix(angle float64) [4]C.double {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return [4]C.double{
+		C.double(cos), C.double(-sin),
+		C.double(sin), C.double(cos),
+	}
+}
+
 func vipsFlip(image *C.VipsImage, direction Direction) (*C.VipsImage, error) {
 	var out *C.VipsImage
 	defer C.g_object_unref(C.gpointer(image))
@@ -181,7 +246,7 @@ func vipsZoom(image *C.VipsImage, zoom int) (*C.VipsImage, error) {
 }
 
 func vipsWatermark(image *C.VipsImage, w Watermark) (*C.VipsImage, error) {
-	var out *C.VipsImage
+	var mask, out *C.VipsImage
 
 	// Defaults
 	noReplicate := 0
@@ -189,17 +254,93 @@ func vipsWatermark(image *C.VipsImage, w Watermark) (*C.VipsImage, error) {
 		noReplicate = 1
 	}
 
+	color := w.Color
+	if color == (Color{}) {
+		color = w.Background
+	}
+
 	text := C.CString(w.Text)
 	font := C.CString(w.Font)
-	background := [3]C.double{C.double(w.Background.R), C.double(w.Background.G), C.double(w.Background.B)}
+	background := [3]C.double{C.double(color.R), C.double(color.G), C.double(color.B)}
+
+	defer C.free(unsafe.Pointer(text))
+	defer C.free(unsafe.Pointer(font))
+
+	textOpts := vipsWatermarkTextOptions{Text: text, Font: font}
+	if w.FontFile != "" {
+		textOpts.FontFile = C.CString(w.FontFile)
+		defer C.free(unsafe.Pointer(textOpts.FontFile))
+	}
 
-	textOpts := vipsWatermarkTextOptions{text, font}
-	opts := vipsWatermarkOptions{C.int(w.Width), C.int(w.DPI), C.int(w.Margin), C.int(noReplicate), C.float(w.Opacity), background}
+	opts := vipsWatermarkOptions{
+		Width:       C.int(w.Width),
+		DPI:         C.int(w.DPI),
+		Margin:      C.int(w.Margin),
+		NoReplicate: C.int(noReplicate),
+		Opacity:     C.float(w.Opacity),
+		Background:  background,
+		Align:       C.int(w.Align),
+		Spacing:     C.int(w.LineSpacing),
+	}
+
+	if w.Angle != 0 {
+		opts.Rotate = 1
+		opts.Matrix = rotationMatrix(w.Angle)
+	}
+
+	err := C.vips_watermark_mask(&mask, (*C.WatermarkTextOptions)(unsafe.Pointer(&textOpts)), (*C.WatermarkOptions)(unsafe.Pointer(&opts)))
+	if err != 0 {
+		C.g_object_unref(C.gpointer(image))
+		return nil, catchVipsError()
+	}
+	defer C.g_object_unref(C.gpointer(mask))
+
+	switch {
+	case !w.Positioned:
+		opts.Left, opts.Top = watermarkOffset, watermarkOffset
+	case w.NoReplicate:
+		left, top := calculatePosition(int(image.Xsize), int(image.Ysize), int(mask.Xsize), int(mask.Ysize), w.Gravity, w.Left, w.Top)
+		opts.Left, opts.Top = C.int(left), C.int(top)
+	default:
+		opts.Left, opts.Top = C.int(w.Left), C.int(w.Top)
+	}
+
+	err = C.vips_watermark(image, mask, &out, (*C.WatermarkOptions)(unsafe.Pointer(&opts)))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
 
+	return out, nil
+}
+
+func vipsRenderText(o TextOptions) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	text := C.CString(o.Text)
+	font := C.CString(o.Font)
 	defer C.free(unsafe.Pointer(text))
 	defer C.free(unsafe.Pointer(font))
 
-	err := C.vips_watermark(image, &out, (*C.WatermarkTextOptions)(unsafe.Pointer(&textOpts)), (*C.WatermarkOptions)(unsafe.Pointer(&opts)))
+	background := backgroundColor(o.Background)
+	opts := vipsRenderTextOptions{
+		Text:        text,
+		Font:        font,
+		Width:       C.int(o.Width),
+		DPI:         C.int(o.DPI),
+		Align:       C.int(o.Align),
+		Justify:     C.int(boolToInt(o.Justify)),
+		Spacing:     C.int(o.LineSpacing),
+		Padding:     C.int(o.Padding),
+		Transparent: C.int(boolToInt(o.Transparent)),
+		Foreground:  [3]C.double{C.double(o.Color.R), C.double(o.Color.G), C.double(o.Color.B)},
+		Background:  [3]C.double{C.double(background.R), C.double(background.G), C.double(background.B)},
+	}
+	if o.FontFile != "" {
+		opts.FontFile = C.CString(o.FontFile)
+		defer C.free(unsafe.Pointer(opts.FontFile))
+	}
+
+	err := C.vips_render_text_bridge(&out, (*C.RenderTextOptions)(unsafe.Pointer(&opts)))
 	if err != 0 {
 		return nil, catchVipsError()
 	}
@@ -268,24 +409,46 @@ func vipsPreSave(image *C.VipsImage, o *vipsSaveOptions) (*C.VipsImage, error) {
 	var outImage *C.VipsImage
 	if vipsColourspaceIsSupported(image) {
 		err := int(C.vips_colourspace_bridge(image, &outImage, interpretation))
+		C.g_object_unref(C.gpointer(image))
 		if err != 0 {
 			return nil, catchVipsError()
 		}
-		C.g_object_unref(C.gpointer(image))
 		image = outImage
 	}
 
+	// Formats without alpha channel would drop it, leaving black backgrounds
+	if !imageTypeHasAlpha(o.Type) && vipsHasAlpha(image) {
+		return vipsFlatten(image, backgroundColor(o.Background))
+	}
+
 	return image, nil
 }
 
 func vipsSave(image *C.VipsImage, o vipsSaveOptions) ([]byte, error) {
-	defer C.g_object_unref(C.gpointer(image))
-
 	image, err := vipsPreSave(image, &o)
 	if err != nil {
 		return nil, err
 	}
+	defer C.g_object_unref(C.gpointer(image))
+
+	if o.AutoQuality.SSIM > 0 {
+		result, err := encodeAutoQuality(image, o)
+		if err != nil || o.MaxBytes == 0 || len(result.Buffer) <= o.MaxBytes {
+			return result.Buffer, err
+		}
+		o.Quality = result.Quality
+	}
 
+	if o.MaxBytes > 0 {
+		return encodeToSize(image, o)
+	}
+
+	return vipsEncode(image, o)
+}
+
+// vipsEncode encodes the image without taking its ownership,
+// so it can be encoded multiple times with different options
+func vipsEncode(image *C.VipsImage, o vipsSaveOptions) ([]byte, error) {
 	length := C.size_t(0)
 	saveErr := C.int(0)
 	interlace := C.int(boolToInt(o.Interlace))
@@ -294,10 +457,10 @@ func vipsSave(image *C.VipsImage, o vipsSaveOptions) ([]byte, error) {
 	var ptr unsafe.Pointer
 	switch o.Type {
 	case WEBP:
-		saveErr = C.vips_webpsave_bridge(image, &ptr, &length, 1, quality)
+		saveErr = C.vips_webpsave_bridge(image, &ptr, &length, 1, quality, C.int(o.Effort))
 		break
 	case PNG:
-		saveErr = C.vips_pngsave_bridge(image, &ptr, &length, 1, C.int(o.Compression), quality, interlace)
+		saveErr = C.vips_pngsave_bridge(image, &ptr, &length, 1, C.int(o.Compression), quality, interlace, C.int(boolToInt(o.Palette)))
 		break
 	default:
 		saveErr = C.vips_jpegsave_bridge(image, &ptr, &length, 1, quality, interlace)
@@ -317,6 +480,224 @@ func vipsSave(image *C.VipsImage, o vipsSaveOptions) ([]byte, error) {
 	return buf, nil
 }
 
+func vipsCopy(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_copy_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+// vipsUnref releases the given reference of the image
+func vipsUnref(image *C.VipsImage) {
+	C.g_object_unref(C.gpointer(image))
+}
+
+// vipsCopyMemory renders the image into memory without taking its ownership.
+// Images already in memory are only referenced
+func vipsCopyMemory(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	err := C.vips_copy_memory_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsSSIM(a, b *C.VipsImage) (float64, error) {
+	var ssim C.double
+
+	err := C.vips_ssim_bridge(a, b, &ssim)
+	if err != 0 {
+		return 0, catchVipsError()
+	}
+
+	return float64(ssim), nil
+}
+
+func vipsCompare(a, b *C.VipsImage, threshold float64, withDiff bool) (vipsCompareResult, *C.VipsImage, error) {
+	var max, mean, mse, pixels C.double
+	var diff *C.VipsImage
+	var diffOut **C.VipsImage
+	if withDiff {
+		diffOut = &diff
+	}
+
+	err := C.vips_compare_bridge(a, b, C.double(threshold), &max, &mean, &mse, &pixels, diffOut)
+	if err != 0 {
+		return vipsCompareResult{}, nil, catchVipsError()
+	}
+
+	result := vipsCompareResult{
+		Max:    float64(max),
+		Mean:   float64(mean),
+		MSE:    float64(mse),
+		Pixels: int(math.Floor(float64(pixels) + 0.5)),
+	}
+
+	return result, diff, nil
+}
+
+func vipsLuminance(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_luminance_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsSRGB(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_srgb_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+// vipsImagePixels returns a copy of the band interleaved image pixels
+func vipsImagePixels(image *C.VipsImage) ([]byte, error) {
+	var ptr unsafe.Pointer
+	length := C.size_t(0)
+
+	err := C.vips_image_pixels_bridge(image, &ptr, &length)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	buf := C.GoBytes(ptr, C.int(length))
+	C.g_free(C.gpointer(ptr))
+
+	return buf, nil
+}
+
+// vipsImageDoubles returns the values of a double image
+func vipsImageDoubles(image *C.VipsImage) ([]float64, error) {
+	if image.BandFmt != C.VIPS_FORMAT_DOUBLE {
+		return nil, errors.New("Image format is not double")
+	}
+
+	buf, err := vipsImagePixels(image)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(buf)/8)
+	for i := range values {
+		values[i] = *(*float64)(unsafe.Pointer(&buf[i*8]))
+	}
+
+	return values, nil
+}
+
+func vipsStats(image *C.VipsImage) ([]float64, error) {
+	var out *C.VipsImage
+
+	err := C.vips_stats_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+	defer C.g_object_unref(C.gpointer(out))
+
+	return vipsImageDoubles(out)
+}
+
+func vipsHistogram(image *C.VipsImage) ([]float64, error) {
+	var out *C.VipsImage
+
+	err := C.vips_hist_find_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+	defer C.g_object_unref(C.gpointer(out))
+
+	return vipsImageDoubles(out)
+}
+
+func vipsBandDifference(image *C.VipsImage) (float64, error) {
+	var diff C.double
+
+	err := C.vips_band_difference_bridge(image, &diff)
+	if err != 0 {
+		return 0, catchVipsError()
+	}
+
+	return float64(diff), nil
+}
+
+func vipsImageFromPixels(pixels []byte, width, height, bands int) (*C.VipsImage, error) {
+	var image *C.VipsImage
+
+	if len(pixels) == 0 || len(pixels) != width*height*bands {
+		return nil, errors.New("Invalid image pixels")
+	}
+
+	err := C.vips_image_from_pixels_bridge(unsafe.Pointer(&pixels[0]), C.size_t(len(pixels)),
+		C.int(width), C.int(height), C.int(bands), &image)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return image, nil
+}
+
+func vipsCanvas(width, height int, colour [4]float64, bands int) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	values := [4]C.double{C.double(colour[0]), C.double(colour[1]), C.double(colour[2]), C.double(colour[3])}
+	err := C.vips_canvas_bridge(&out, C.int(width), C.int(height), &values[0], C.int(bands))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+// vipsGradient maps the gradient offsets through the lut image,
+// without taking its ownership
+func vipsGradient(lut *C.VipsImage, width, height int, radial bool, cos, sin, length float64) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	err := C.vips_gradient_bridge(lut, &out, C.int(width), C.int(height), C.int(boolToInt(radial)),
+		C.double(cos), C.double(sin), C.double(length))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsFindTrim(image *C.VipsImage, o Trim) (Area, error) {
+	var left, top, width, height C.int
+
+	threshold := o.Threshold
+	if threshold == 0 {
+		threshold = 10
+	}
+
+	err := C.vips_find_trim_bridge(image, &left, &top, &width, &height, C.double(threshold),
+		C.double(o.Background.R), C.double(o.Background.G), C.double(o.Background.B),
+		C.int(boolToInt(o.AutoBackground)))
+	if err != 0 {
+		return Area{}, catchVipsError()
+	}
+
+	return Area{Left: int(left), Top: int(top), Width: int(width), Height: int(height)}, nil
+}
+
 func vipsExtract(image *C.VipsImage, left, top, width, height int) (*C.VipsImage, error) {
 	var buf *C.VipsImage
 	defer C.g_object_unref(C.gpointer(image))
@@ -386,6 +767,25 @@ func vipsAffine(input *C.VipsImage, residualx, residualy float64, i Interpolator
 	return image, nil
 }
 
+// vipsAffineAlpha scales the image like vipsAffine, premultiplying
+// its alpha channel to keep the colours of the transparent edges
+func vipsAffineAlpha(input *C.VipsImage, residualx, residualy float64, i Interpolator) (*C.VipsImage, error) {
+	var image *C.VipsImage
+	cstring := C.CString(i.String())
+	interpolator := C.vips_interpolate_new(cstring)
+
+	defer C.free(unsafe.Pointer(cstring))
+	defer C.g_object_unref(C.gpointer(input))
+	defer C.g_object_unref(C.gpointer(interpolator))
+
+	err := C.vips_affine_alpha_bridge(input, &image, C.double(residualx), C.double(residualy), interpolator)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return image, nil
+}
+
 func vipsImageType(bytes []byte) ImageType {
 	if len(bytes) == 0 {
 		return UNKNOWN
@@ -433,6 +833,148 @@ func boolToInt(b bool) int {
 	return 0
 }
 
+func vipsWatermarkImage(image, sub *C.VipsImage, left, top int, opacity float64, tile bool, spacing int) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_watermark_image_bridge(image, sub, &out, C.int(left), C.int(top),
+		C.double(opacity), C.int(boolToInt(tile)), C.int(spacing))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsComposite(image, sub *C.VipsImage, mode BlendMode, left, top int, opacity float64) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_composite_layer_bridge(image, sub, &out, C.int(mode),
+		C.int(left), C.int(top), C.double(opacity))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsExtractBand(image *C.VipsImage, band, n int) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_extract_band_bridge(image, &out, C.int(band), C.int(n))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+// vipsBandJoin joins the bands of the images,
+// without taking their ownership
+func vipsBandJoin(images []*C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	err := C.vips_bandjoin_bridge(&images[0], &out, C.int(len(images)))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsReorderBands(image *C.VipsImage, order []int) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	bands := make([]C.int, len(order))
+	for i, band := range order {
+		bands[i] = C.int(band)
+	}
+
+	err := C.vips_reorder_bands_bridge(image, &out, &bands[0], C.int(len(bands)))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsBandBool(image *C.VipsImage, operation BandBoolOperation) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_bandbool_bridge(image, &out, C.int(operation))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsBandsInterpretation(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_bands_interpretation_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsFlatten(image *C.VipsImage, background Color) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_flatten_bridge(image, &out,
+		C.double(background.R), C.double(background.G), C.double(background.B))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsAlpha(image *C.VipsImage, operation AlphaOperation) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_alpha_bridge(image, &out, C.int(operation))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsAlphaMask(image *C.VipsImage) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_alpha_mask_bridge(image, &out)
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
+func vipsMask(image, mask *C.VipsImage, flatten bool, background Color) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_mask_bridge(image, mask, &out, C.int(boolToInt(flatten)),
+		C.double(background.R), C.double(background.G), C.double(background.B))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+
+	return out, nil
+}
+
 func vipsGaussianBlur(image *C.VipsImage, o GaussianBlur) (*C.VipsImage, error) {
 	var out *C.VipsImage
 	defer C.g_object_unref(C.gpointer(image))
@@ -443,3 +985,62 @@ func vipsGaussianBlur(image *C.VipsImage, o GaussianBlur) (*C.VipsImage, error)
 	}
 	return out, nil
 }
+
+func vipsAdjust(image *C.VipsImage, o Adjust) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	err := C.vips_adjust_bridge(image, &out, C.double(o.Brightness), C.double(o.Contrast),
+		C.double(o.Gamma), C.double(o.Saturation), C.double(o.Hue))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+	return out, nil
+}
+
+func vipsEffects(image *C.VipsImage, o Effects) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	shadow := [3]C.double{C.double(o.Duotone.Shadow.R), C.double(o.Duotone.Shadow.G), C.double(o.Duotone.Shadow.B)}
+	highlight := [3]C.double{C.double(o.Duotone.Highlight.R), C.double(o.Duotone.Highlight.G), C.double(o.Duotone.Highlight.B)}
+
+	err := C.vips_effects_bridge(image, &out, C.int(boolToInt(o.Greyscale)), C.int(boolToInt(o.Sepia)),
+		C.int(boolToInt(o.Duotone != (Duotone{}))), &shadow[0], &highlight[0],
+		C.int(o.Posterise), C.double(o.Threshold), C.int(boolToInt(o.Invert)))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+	return out, nil
+}
+
+func vipsSharpen(image *C.VipsImage, o Sharpen) (*C.VipsImage, error) {
+	var out *C.VipsImage
+	defer C.g_object_unref(C.gpointer(image))
+
+	if o.Sigma == 0 {
+		o.Sigma = 0.5
+	}
+	if o.X1 == 0 {
+		o.X1 = 2
+	}
+	if o.Y2 == 0 {
+		o.Y2 = 10
+	}
+	if o.Y3 == 0 {
+		o.Y3 = 20
+	}
+	if o.M2 == 0 {
+		o.M2 = 3
+		if o.M1 == 0 && o.Amount > 0 {
+			o.M2 = 3 * o.Amount
+		}
+	}
+
+	err := C.vips_sharpen_bridge(image, &out, C.double(o.Sigma), C.double(o.X1),
+		C.double(o.Y2), C.double(o.Y3), C.double(o.M1), C.double(o.M2))
+	if err != 0 {
+		return nil, catchVipsError()
+	}
+	return out, nil
+}
@@ -1,4 +1,5 @@
 #include <stdlib.h>
+#include <string.h>
 #include <vips/vips.h>
 #include <vips/vips7compat.h>
 
@@ -34,6 +35,7 @@ enum types {
 typedef struct {
 	const char *Text;
 	const char *Font;
+	const char *FontFile;
 } WatermarkTextOptions;
 
 typedef struct {
@@ -43,8 +45,29 @@ typedef struct {
 	int    NoReplicate;
 	float  Opacity;
 	double Background[3];
+	int    Left;
+	int    Top;
+	int    Align;
+	int    Spacing;
+	int    Rotate;
+	double Matrix[4];
 } WatermarkOptions;
 
+typedef struct {
+	const char *Text;
+	const char *Font;
+	const char *FontFile;
+	int    Width;
+	int    DPI;
+	int    Align;
+	int    Justify;
+	int    Spacing;
+	int    Padding;
+	int    Transparent;
+	double Foreground[3];
+	double Background[3];
+} RenderTextOptions;
+
 static int
 has_profile_embed(VipsImage *image) {
 	return vips_image_get_typeof(image, VIPS_META_ICC_NAME);
@@ -100,6 +123,29 @@ vips_affine_interpolator(VipsImage *in, VipsImage **out, double a, double b, dou
 	return vips_affine(in, out, a, b, c, d, "interpolate", interpolator, NULL);
 }
 
+/**
+ * Scales the image like vips_affine_interpolator, premultiplying
+ * the colours of transparent images to avoid dark fringes.
+ */
+int
+vips_affine_alpha_bridge(VipsImage *in, VipsImage **out, double a, double d, VipsInterpolate *interpolator) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+	if (has_alpha_channel(in)) {
+		VipsImage *base = vips_image_new();
+		VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+
+		int code = vips_premultiply(in, &t[0], NULL) ||
+			vips_affine(t[0], &t[1], a, 0, 0, d, "interpolate", interpolator, "premultiplied", TRUE, NULL) ||
+			vips_unpremultiply(t[1], &t[2], NULL) ||
+			vips_cast(t[2], out, in->BandFmt, NULL);
+
+		g_object_unref(base);
+		return code;
+	}
+#endif
+	return vips_affine(in, out, a, 0, 0, d, "interpolate", interpolator, NULL);
+}
+
 int
 vips_jpegload_buffer_shrink(void *buf, size_t len, VipsImage **out, int shrink) {
 	return vips_jpegload_buffer(buf, len, out, "shrink", shrink, NULL);
@@ -116,7 +162,7 @@ vips_shrink_bridge(VipsImage *in, VipsImage **out, double xshrink, double yshrin
 }
 
 int
-vips_rotate(VipsImage *in, VipsImage **out, int angle) {
+vips_rotate_bridge(VipsImage *in, VipsImage **out, int angle) {
 	int rotate = VIPS_ANGLE_D0;
 
 	if (angle == 90) {
@@ -171,6 +217,11 @@ vips_extract_area_bridge(VipsImage *in, VipsImage **out, int left, int top, int
 	return vips_extract_area(in, out, left, top, width, height, NULL);
 }
 
+int
+vips_copy_bridge(VipsImage *in, VipsImage **out) {
+	return vips_copy(in, out, NULL);
+}
+
 int
 vips_colourspace_issupported_bridge(VipsImage *in) {
 	return vips_colourspace_issupported(in) ? 1 : 0;
@@ -198,7 +249,20 @@ vips_jpegsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int qual
 }
 
 int
-vips_pngsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int compression, int quality, int interlace) {
+vips_pngsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int compression, int quality, int interlace, int palette) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 7))
+	if (palette > 0) {
+		return vips_pngsave_buffer(in, buf, len,
+			"strip", FALSE,
+			"compression", compression,
+			"interlace", with_interlace(interlace),
+			"filter", VIPS_FOREIGN_PNG_FILTER_NONE,
+			"palette", TRUE,
+			"Q", quality,
+			NULL
+		);
+	}
+#endif
 #if (VIPS_MAJOR_VERSION >= 8 || (VIPS_MAJOR_VERSION >= 7 && VIPS_MINOR_VERSION >= 42))
 	return vips_pngsave_buffer(in, buf, len,
 		"strip", FALSE,
@@ -218,7 +282,17 @@ vips_pngsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int compr
 }
 
 int
-vips_webpsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int quality) {
+vips_webpsave_bridge(VipsImage *in, void **buf, size_t *len, int strip, int quality, int effort) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 8))
+	if (effort > 0) {
+		return vips_webpsave_buffer(in, buf, len,
+			"strip", strip,
+			"Q", quality,
+			"reduction_effort", effort,
+			NULL
+		);
+	}
+#endif
 	return vips_webpsave_buffer(in, buf, len,
 		"strip", strip,
 		"Q", quality,
@@ -248,14 +322,18 @@ vips_init_image (void *buf, size_t len, int imageType, VipsImage **out) {
 }
 
 int
-vips_watermark_replicate (VipsImage *orig, VipsImage *in, VipsImage **out) {
+vips_watermark_replicate (VipsImage *orig, VipsImage *in, VipsImage **out, int left, int top) {
 	VipsImage *cache = vips_image_new();
 
+	// Tiles start at the given position
+	int x = ((-left % in->Xsize) + in->Xsize) % in->Xsize;
+	int y = ((-top % in->Ysize) + in->Ysize) % in->Ysize;
+
 	if (
 		vips_replicate(in, &cache,
-			1 + orig->Xsize / in->Xsize,
-			1 + orig->Ysize / in->Ysize, NULL) ||
-		vips_crop(cache, out, 0, 0, orig->Xsize, orig->Ysize, NULL)
+			2 + orig->Xsize / in->Xsize,
+			2 + orig->Ysize / in->Ysize, NULL) ||
+		vips_crop(cache, out, x, y, orig->Xsize, orig->Ysize, NULL)
 	) {
 		g_object_unref(cache);
 		return 1;
@@ -265,55 +343,166 @@ vips_watermark_replicate (VipsImage *orig, VipsImage *in, VipsImage **out) {
 	return 0;
 }
 
-int
-vips_watermark(VipsImage *in, VipsImage **out, WatermarkTextOptions *to, WatermarkOptions *o) {
-	double ones[3] = { 1, 1, 1 };
+/**
+ * Renders the text, which may use Pango markup, as a one band mask.
+ * Alignment requires libvips 8.0+, justification and spacing 8.8+, font files 8.9+.
+ */
+static int
+vips_text_bridge(VipsImage **out, const char *text, const char *font, const char *fontfile, int width, int dpi, int align, int justify, int spacing) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 9))
+	return vips_text(out, text,
+		"width", width,
+		"dpi", dpi,
+		"font", font,
+		"fontfile", fontfile,
+		"align", align,
+		"justify", justify,
+		"spacing", spacing,
+		NULL);
+#elif (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 8)
+	if (fontfile != NULL) {
+		vips_error("bimg", "font files require libvips 8.9+");
+		return 1;
+	}
+	return vips_text(out, text,
+		"width", width,
+		"dpi", dpi,
+		"font", font,
+		"align", align,
+		"justify", justify,
+		"spacing", spacing,
+		NULL);
+#elif (VIPS_MAJOR_VERSION >= 8)
+	if (fontfile != NULL) {
+		vips_error("bimg", "font files require libvips 8.9+");
+		return 1;
+	}
+	return vips_text(out, text,
+		"width", width,
+		"dpi", dpi,
+		"font", font,
+		"align", align,
+		NULL);
+#else
+	return vips_text(out, text,
+		"width", width,
+		"dpi", dpi,
+		"font", font,
+		NULL);
+#endif
+}
 
+/**
+ * Renders the text as a one band mask, rotated by the given
+ * matrix if requested, whose values are scaled by the opacity.
+ */
+int
+vips_watermark_mask(VipsImage **out, WatermarkTextOptions *to, WatermarkOptions *o) {
 	VipsImage *base = vips_image_new();
-	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 10);
-	t[0] = in;
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+	VipsImage *mask;
+
+	if (vips_text_bridge(&t[0], to->Text, to->Font, to->FontFile, o->Width, o->DPI, o->Align, 0, o->Spacing)) {
+		g_object_unref(base);
+		return 1;
+	}
+	mask = t[0];
+
+	if (o->Rotate) {
+		if (vips_affine(mask, &t[1], o->Matrix[0], o->Matrix[1], o->Matrix[2], o->Matrix[3], NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		mask = t[1];
+	}
 
-	// Make the mask.
 	if (
-		vips_text(&t[1], to->Text,
-			"width", o->Width,
-			"dpi", o->DPI,
-			"font", to->Font,
-			NULL) ||
-		vips_linear1(t[1], &t[2], o->Opacity, 0.0, NULL) ||
-		vips_cast(t[2], &t[3], VIPS_FORMAT_UCHAR, NULL) ||
-		vips_embed(t[3], &t[4], 100, 100, t[3]->Xsize + o->Margin, t[3]->Ysize + o->Margin, NULL)
-		) {
+		vips_linear1(mask, &t[2], o->Opacity, 0.0, NULL) ||
+		vips_cast(t[2], out, VIPS_FORMAT_UCHAR, NULL)
+	) {
 		g_object_unref(base);
 		return 1;
 	}
 
-	// Replicate if necessary
+	g_object_unref(base);
+	return 0;
+}
+
+/**
+ * Paints the text colour through the mask, either placed once at
+ * the given position or tiled from it with the given margin.
+ */
+int
+vips_watermark(VipsImage *in, VipsImage *mask, VipsImage **out, WatermarkOptions *o) {
+	double ones[4] = { 1, 1, 1, 1 };
+	double colour[4] = { o->Background[0], o->Background[1], o->Background[2], 255 };
+	int alpha = has_alpha_channel(in);
+	int bands = in->Bands - alpha;
+
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 11);
+	t[0] = in;
+
+	// Only grey and RGB images can be painted, others such as CMYK are converted to sRGB
+	if (in->Type == VIPS_INTERPRETATION_CMYK || (bands != 1 && bands != 3)) {
+		if (!vips_colourspace_issupported(in)) {
+			vips_error("bimg", "Cannot paint the watermark over %d bands images", in->Bands);
+			g_object_unref(base);
+			return 1;
+		}
+		if (vips_colourspace(in, &t[10], VIPS_INTERPRETATION_sRGB, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		in = t[10];
+		alpha = has_alpha_channel(in);
+		bands = in->Bands - alpha;
+		if (bands != 3 || in->Bands > 4) {
+			vips_error("bimg", "Cannot paint the watermark over %d bands images", in->Bands);
+			g_object_unref(base);
+			return 1;
+		}
+	}
+
+	// Grey images are painted with the colour luminance
+	if (bands < 3) {
+		colour[0] = (colour[0] + colour[1] + colour[2]) / 3;
+		colour[1] = colour[3];
+	}
+	if (in->BandFmt == VIPS_FORMAT_USHORT) {
+		for (int i = 0; i < 4; i++) {
+			colour[i] *= 257;
+		}
+	}
+
+	// Place or replicate the mask over the image
 	if (o->NoReplicate != 1) {
-		VipsImage *cache = vips_image_new();
-		if (vips_watermark_replicate(t[0], t[4], &cache)) {
-			g_object_unref(cache);
+		if (
+			vips_embed(mask, &t[1], 0, 0, mask->Xsize + o->Margin, mask->Ysize + o->Margin, NULL) ||
+			vips_watermark_replicate(in, t[1], &t[2], o->Left, o->Top)
+		) {
 			g_object_unref(base);
 			return 1;
 		}
-		g_object_unref(t[4]);
-		t[4] = cache;
+	} else if (vips_embed(mask, &t[2], o->Left, o->Top, in->Xsize, in->Ysize, NULL)) {
+		g_object_unref(base);
+		return 1;
 	}
 
 	// Make the constant image to paint the text with.
 	if (
 		vips_black(&t[5], 1, 1, NULL) ||
-		vips_linear(t[5], &t[6], ones, o->Background, 3, NULL) ||
-		vips_cast(t[6], &t[7], VIPS_FORMAT_UCHAR, NULL) ||
-		vips_copy(t[7], &t[8], "interpretation", t[0]->Type, NULL) ||
-		vips_embed(t[8], &t[9], 0, 0, t[0]->Xsize, t[0]->Ysize, "extend", VIPS_EXTEND_COPY, NULL)
+		vips_linear(t[5], &t[6], ones, colour, in->Bands, NULL) ||
+		vips_cast(t[6], &t[7], in->BandFmt, NULL) ||
+		vips_copy(t[7], &t[8], "interpretation", in->Type, NULL) ||
+		vips_embed(t[8], &t[9], 0, 0, in->Xsize, in->Ysize, "extend", VIPS_EXTEND_COPY, NULL)
 		) {
 		g_object_unref(base);
 		return 1;
 	}
 
 	// Blend the mask and text and write to output.
-	if (vips_ifthenelse(t[4], t[9], t[0], out, "blend", TRUE, NULL)) {
+	if (vips_ifthenelse(t[2], t[9], in, out, "blend", TRUE, NULL)) {
 		g_object_unref(base);
 		return 1;
 	}
@@ -322,6 +511,62 @@ vips_watermark(VipsImage *in, VipsImage **out, WatermarkTextOptions *to, Waterma
 	return 0;
 }
 
+/**
+ * Paints the text with the foreground colour over the background colour,
+ * or over a transparent background, surrounded by the padding.
+ */
+int
+vips_render_text_bridge(VipsImage **out, RenderTextOptions *o) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 6);
+	double zeros[3] = { 0, 0, 0 };
+	double scale[3];
+	double background[4] = { o->Background[0], o->Background[1], o->Background[2], 255 };
+
+	if (vips_text_bridge(&t[0], o->Text, o->Font, o->FontFile, o->Width, o->DPI, o->Align, o->Justify, o->Spacing)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	if (o->Transparent) {
+		// The mask becomes the alpha of a constant colour image
+		if (
+			vips_linear(t[0], &t[1], zeros, o->Foreground, 3, NULL) ||
+			vips_bandjoin2(t[1], t[0], &t[2], NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		background[3] = 0;
+	} else {
+		// Blend from the background to the foreground colour along the mask
+		for (int i = 0; i < 3; i++) {
+			scale[i] = (o->Foreground[i] - o->Background[i]) / 255.0;
+		}
+		if (vips_linear(t[0], &t[2], scale, o->Background, 3, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+	}
+
+	if (
+		vips_cast(t[2], &t[3], VIPS_FORMAT_UCHAR, NULL) ||
+		vips_copy(t[3], &t[4], "interpretation", VIPS_INTERPRETATION_sRGB, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	VipsArrayDouble *array = vips_array_double_new(background, t[4]->Bands);
+	int code = vips_embed(t[4], out, o->Padding, o->Padding,
+		t[4]->Xsize + 2 * o->Padding, t[4]->Ysize + 2 * o->Padding,
+		"extend", VIPS_EXTEND_BACKGROUND, "background", array, NULL);
+	vips_area_unref(VIPS_AREA(array));
+
+	g_object_unref(base);
+	return code;
+}
+
 int
 vips_gaussblur_bridge(VipsImage *in, VipsImage **out, double sigma, double min_ampl) {
 #if (VIPS_MAJOR_VERSION == 7 && VIPS_MINOR_VERSION < 41)
@@ -330,3 +575,1190 @@ vips_gaussblur_bridge(VipsImage *in, VipsImage **out, double sigma, double min_a
 	return vips_gaussblur(in, out, sigma, NULL, "min_ampl", min_ampl, NULL);
 #endif
 }
+
+int
+vips_sharpen_bridge(VipsImage *in, VipsImage **out, double sigma, double x1, double y2, double y3, double m1, double m2) {
+#if (VIPS_MAJOR_VERSION == 7 && VIPS_MINOR_VERSION < 41)
+	vips_error("bimg", "sharpen requires libvips 7.41+");
+	return 1;
+#elif (VIPS_MAJOR_VERSION == 7 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION < 5))
+	// Older versions take an integer radius instead of the gaussian sigma
+	int radius = (int) (sigma * 2 + 0.5);
+	return vips_sharpen(in, out, "radius", radius > 0 ? radius : 1,
+		"x1", x1, "y2", y2, "y3", y3, "m1", m1, "m2", m2, NULL);
+#else
+	return vips_sharpen(in, out, "sigma", sigma,
+		"x1", x1, "y2", y2, "y3", y3, "m1", m1, "m2", m2, NULL);
+#endif
+}
+
+static int
+vips_luminance(VipsImage *in, VipsImage **out) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 2);
+
+	if (vips_colourspace_issupported(in)) {
+		if (vips_colourspace(in, &t[0], VIPS_INTERPRETATION_B_W, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+	} else {
+		g_object_ref(in);
+		t[0] = in;
+	}
+
+	if (
+		vips_extract_band(t[0], &t[1], 0, NULL) ||
+		vips_cast(t[1], out, VIPS_FORMAT_FLOAT, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	g_object_unref(base);
+	return 0;
+}
+
+/**
+ * Computes the mean structural similarity (SSIM) index of the
+ * luminance of two images of the same size, using a gaussian window.
+ * See: https://en.wikipedia.org/wiki/Structural_similarity
+ */
+int
+vips_ssim_bridge(VipsImage *a, VipsImage *b, double *out) {
+	// (0.01 * 255)^2 and (0.03 * 255)^2
+	double c1 = 6.5025, c2 = 58.5225;
+
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 25);
+
+	if (
+		vips_luminance(a, &t[0]) ||
+		vips_luminance(b, &t[1]) ||
+		// Local means
+		vips_gaussblur_bridge(t[0], &t[2], 1.5, 0.2) ||
+		vips_gaussblur_bridge(t[1], &t[3], 1.5, 0.2) ||
+		// Local variances and covariance
+		vips_multiply(t[0], t[0], &t[4], NULL) ||
+		vips_multiply(t[1], t[1], &t[5], NULL) ||
+		vips_multiply(t[0], t[1], &t[6], NULL) ||
+		vips_gaussblur_bridge(t[4], &t[7], 1.5, 0.2) ||
+		vips_gaussblur_bridge(t[5], &t[8], 1.5, 0.2) ||
+		vips_gaussblur_bridge(t[6], &t[9], 1.5, 0.2) ||
+		vips_multiply(t[2], t[2], &t[10], NULL) ||
+		vips_multiply(t[3], t[3], &t[11], NULL) ||
+		vips_multiply(t[2], t[3], &t[12], NULL) ||
+		vips_subtract(t[7], t[10], &t[13], NULL) ||
+		vips_subtract(t[8], t[11], &t[14], NULL) ||
+		vips_subtract(t[9], t[12], &t[15], NULL) ||
+		// (2 * mu_ab + c1) * (2 * sigma_ab + c2)
+		vips_linear1(t[12], &t[16], 2, c1, NULL) ||
+		vips_linear1(t[15], &t[17], 2, c2, NULL) ||
+		vips_multiply(t[16], t[17], &t[18], NULL) ||
+		// (mu_a^2 + mu_b^2 + c1) * (sigma_a^2 + sigma_b^2 + c2)
+		vips_add(t[10], t[11], &t[19], NULL) ||
+		vips_add(t[13], t[14], &t[20], NULL) ||
+		vips_linear1(t[19], &t[21], 1, c1, NULL) ||
+		vips_linear1(t[20], &t[22], 1, c2, NULL) ||
+		vips_multiply(t[21], t[22], &t[23], NULL) ||
+		// Mean SSIM
+		vips_divide(t[18], t[23], &t[24], NULL) ||
+		vips_avg(t[24], out, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	g_object_unref(base);
+	return 0;
+}
+
+static int
+vips_compare_normalize(VipsImage *in, VipsImage **out) {
+	if (vips_colourspace_issupported(in)) {
+		return vips_colourspace(in, out, VIPS_INTERPRETATION_sRGB, NULL);
+	}
+	return vips_copy(in, out, NULL);
+}
+
+/**
+ * Compares two images of the same size, computing the maximum, mean and
+ * squared mean of the absolute difference, plus the number of pixels
+ * whose difference in any band is above the threshold.
+ * If diff is not NULL, a heatmap of the differences is also generated.
+ */
+int
+vips_compare_bridge(VipsImage *a, VipsImage *b, double threshold, double *max, double *mean, double *mse, double *pixels, VipsImage **diff) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 12);
+
+	if (
+		// Only the bands present in both images are compared
+		vips_compare_normalize(a, &t[0]) ||
+		vips_compare_normalize(b, &t[1]) ||
+		vips_extract_band(t[0], &t[2], 0, "n", VIPS_MIN(t[0]->Bands, t[1]->Bands), NULL) ||
+		vips_extract_band(t[1], &t[3], 0, "n", VIPS_MIN(t[0]->Bands, t[1]->Bands), NULL) ||
+		vips_subtract(t[2], t[3], &t[4], NULL) ||
+		vips_abs(t[4], &t[5], NULL) ||
+		vips_max(t[5], max, NULL) ||
+		vips_avg(t[5], mean, NULL) ||
+		vips_multiply(t[5], t[5], &t[6], NULL) ||
+		vips_avg(t[6], mse, NULL) ||
+		vips_more_const1(t[5], &t[7], threshold, NULL) ||
+		vips_bandbool(t[7], &t[8], VIPS_OPERATION_BOOLEAN_OR, NULL) ||
+		vips_avg(t[8], pixels, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	// Relational operations output 255 for true
+	*pixels = *pixels / 255.0 * t[8]->Xsize * t[8]->Ysize;
+
+	if (diff != NULL) {
+		double scale = *max > 0 ? 255.0 / *max : 0;
+		if (
+			vips_bandmean(t[5], &t[9], NULL) ||
+			vips_linear1(t[9], &t[10], scale, 0, NULL) ||
+			vips_cast(t[10], &t[11], VIPS_FORMAT_UCHAR, NULL) ||
+			vips_falsecolour(t[11], diff, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+	}
+
+	g_object_unref(base);
+	return 0;
+}
+
+int
+vips_luminance_bridge(VipsImage *in, VipsImage **out) {
+	VipsImage *luminance;
+
+	if (vips_luminance(in, &luminance)) {
+		return 1;
+	}
+
+	int code = vips_cast(luminance, out, VIPS_FORMAT_UCHAR, NULL);
+	g_object_unref(luminance);
+	return code;
+}
+
+/**
+ * Copies the band interleaved pixels of the image into a new buffer,
+ * which must be released with g_free().
+ */
+int
+vips_image_pixels_bridge(VipsImage *in, void **buf, size_t *len) {
+	VipsImage *memory = vips_image_new_memory();
+
+	if (vips_image_write(in, memory)) {
+		g_object_unref(memory);
+		return 1;
+	}
+
+	*len = VIPS_IMAGE_SIZEOF_IMAGE(memory);
+	*buf = g_malloc(*len);
+	memcpy(*buf, VIPS_IMAGE_ADDR(memory, 0, 0), *len);
+
+	g_object_unref(memory);
+	return 0;
+}
+
+int
+vips_srgb_bridge(VipsImage *in, VipsImage **out) {
+	VipsImage *srgb;
+
+	if (vips_colourspace_issupported(in)) {
+		if (vips_colourspace(in, &srgb, VIPS_INTERPRETATION_sRGB, NULL)) {
+			return 1;
+		}
+	} else if (vips_copy(in, &srgb, NULL)) {
+		return 1;
+	}
+
+	int code = vips_cast(srgb, out, VIPS_FORMAT_UCHAR, NULL);
+	g_object_unref(srgb);
+	return code;
+}
+
+static void
+vips_free_pixels(VipsImage *image, void *pixels) {
+	g_free(pixels);
+}
+
+/**
+ * Creates an 8 bits image from band interleaved pixels.
+ * Pixels are copied, so the caller keeps their ownership.
+ */
+int
+vips_image_from_pixels_bridge(void *pixels, size_t len, int width, int height, int bands, VipsImage **out) {
+#if (VIPS_MAJOR_VERSION >= 8)
+	void *copy = g_malloc(len);
+	memcpy(copy, pixels, len);
+
+	*out = vips_image_new_from_memory(copy, len, width, height, bands, VIPS_FORMAT_UCHAR);
+	if (*out == NULL) {
+		g_free(copy);
+		return 1;
+	}
+
+	g_signal_connect(*out, "postclose", G_CALLBACK(vips_free_pixels), copy);
+	(*out)->Type = bands >= 3 ? VIPS_INTERPRETATION_sRGB : VIPS_INTERPRETATION_B_W;
+
+	return 0;
+#else
+	vips_error("bimg", "creating images from pixels requires libvips 8.0+");
+	return 1;
+#endif
+}
+
+/**
+ * Creates an 8 bits sRGB image filled with the given colour,
+ * whose alpha is only used if the image has 4 bands.
+ */
+int
+vips_canvas_bridge(VipsImage **out, int width, int height, double *colour, int bands) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+	double zeros[4] = { 0, 0, 0, 0 };
+
+	if (
+		vips_black(&t[0], width, height, NULL) ||
+		vips_linear(t[0], &t[1], zeros, colour, bands, NULL) ||
+		vips_cast(t[1], &t[2], VIPS_FORMAT_UCHAR, NULL) ||
+		vips_copy(t[2], out, "interpretation", VIPS_INTERPRETATION_sRGB, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	g_object_unref(base);
+	return 0;
+}
+
+/**
+ * Creates a gradient image mapping the offset of every pixel centre,
+ * from 0 to 1, through the 256 colours of the lut image.
+ * Linear gradients go along the given direction over the given length,
+ * radial gradients go from the image centre to its corners.
+ */
+int
+vips_gradient_bridge(VipsImage *lut, VipsImage **out, int width, int height, int radial, double cos, double sin, double length) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 9);
+	VipsImage *offset;
+
+	if (vips_xyz(&t[0], width, height, NULL)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	if (radial) {
+		// Distance to the centre, normalised by the half sizes
+		double a[2] = { 2.0 / width, 2.0 / height };
+		double b[2] = { (1.0 - width) / width, (1.0 - height) / height };
+		double half[2] = { 0.5, 0.5 };
+
+		t[1] = vips_image_new_matrix_from_array(2, 1, half, 2);
+		if (
+			t[1] == NULL ||
+			vips_linear(t[0], &t[2], a, b, 2, NULL) ||
+			vips_multiply(t[2], t[2], &t[3], NULL) ||
+			vips_recomb(t[3], &t[4], t[1], NULL) ||
+			vips_pow_const1(t[4], &t[5], 0.5, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		offset = t[5];
+	} else {
+		// Projection of the pixel centre over the gradient line
+		double direction[2] = { cos / length, sin / length };
+		double centre = ((0.5 - width / 2.0) * cos + (0.5 - height / 2.0) * sin) / length + 0.5;
+
+		t[1] = vips_image_new_matrix_from_array(2, 1, direction, 2);
+		if (
+			t[1] == NULL ||
+			vips_recomb(t[0], &t[2], t[1], NULL) ||
+			vips_linear1(t[2], &t[5], 1, centre, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		offset = t[5];
+	}
+
+	// Offsets out of the 0 to 1 range are clipped by the cast
+	if (
+		vips_linear1(offset, &t[6], 255, 0.5, NULL) ||
+		vips_cast(t[6], &t[7], VIPS_FORMAT_UCHAR, NULL) ||
+		vips_maplut(t[7], &t[8], lut, NULL) ||
+		vips_copy(t[8], out, "interpretation", VIPS_INTERPRETATION_sRGB, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	g_object_unref(base);
+	return 0;
+}
+
+/**
+ * Computes the statistics of the image as a double matrix,
+ * a row per band after the row of all bands. See vips_stats().
+ */
+int
+vips_stats_bridge(VipsImage *in, VipsImage **out) {
+	return vips_stats(in, out, NULL);
+}
+
+/**
+ * Computes the histogram of every band as a double image,
+ * a pixel per possible value.
+ */
+int
+vips_hist_find_bridge(VipsImage *in, VipsImage **out) {
+	VipsImage *hist;
+
+	if (vips_hist_find(in, &hist, NULL)) {
+		return 1;
+	}
+
+	int code = vips_cast(hist, out, VIPS_FORMAT_DOUBLE, NULL);
+	g_object_unref(hist);
+	return code;
+}
+
+/**
+ * Computes the largest difference between the red,
+ * green and blue bands of an sRGB image.
+ */
+int
+vips_band_difference_bridge(VipsImage *in, double *out) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 7);
+
+	if (
+		vips_extract_band(in, &t[0], 0, NULL) ||
+		vips_extract_band(in, &t[1], 1, NULL) ||
+		vips_extract_band(in, &t[2], 2, NULL) ||
+		vips_subtract(t[0], t[1], &t[3], NULL) ||
+		vips_subtract(t[1], t[2], &t[4], NULL) ||
+		vips_abs(t[3], &t[5], NULL) ||
+		vips_abs(t[4], &t[6], NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	double rg, gb;
+	if (vips_max(t[5], &rg, NULL) || vips_max(t[6], &gb, NULL)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	*out = rg > gb ? rg : gb;
+
+	g_object_unref(base);
+	return 0;
+}
+
+/**
+ * Adjusts the brightness, contrast and gamma of the colour bands,
+ * then their saturation and hue in LCh space. Zero values leave
+ * the image unchanged and the alpha channel is preserved.
+ */
+int
+vips_adjust_bridge(VipsImage *in, VipsImage **out, double brightness, double contrast, double gamma, double saturation, double hue) {
+#if (VIPS_MAJOR_VERSION >= 8)
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 10);
+	VipsImage *colour = in;
+	VipsImage *alpha = NULL;
+	VipsInterpretation interpretation = vips_image_guess_interpretation(in);
+
+	if (has_alpha_channel(in)) {
+		if (
+			vips_extract_band(in, &t[0], 0, "n", in->Bands - 1, NULL) ||
+			vips_extract_band(in, &t[1], in->Bands - 1, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[0];
+		alpha = t[1];
+	}
+
+	if (brightness != 0 || contrast != 0) {
+		double middle = in->BandFmt == VIPS_FORMAT_USHORT ? 32768 : 128;
+		double a = (1 + brightness) * (1 + contrast);
+		double b = -middle * contrast;
+
+		// Cast back to the input format, clipping out of range values
+		if (
+			vips_linear1(colour, &t[2], a, b, NULL) ||
+			vips_cast(t[2], &t[3], in->BandFmt, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[3];
+	}
+
+	if (gamma != 0) {
+		if (vips_gamma(colour, &t[4], "exponent", gamma, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[4];
+	}
+
+	if ((saturation != 0 || hue != 0) && colour->Bands >= 3 && vips_colourspace_issupported(colour)) {
+		double a[3] = { 1, 1 + saturation, 1 };
+		double b[3] = { 0, 0, hue };
+
+		if (
+			vips_colourspace(colour, &t[5], VIPS_INTERPRETATION_LCH, NULL) ||
+			vips_linear(t[5], &t[6], a, b, 3, NULL) ||
+			vips_colourspace(t[6], &t[7], interpretation, NULL) ||
+			vips_cast(t[7], &t[8], in->BandFmt, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[8];
+	}
+
+	if (alpha != NULL) {
+		if (vips_bandjoin2(colour, alpha, &t[9], NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[9];
+	}
+
+	int code = vips_cast(colour, out, in->BandFmt, NULL);
+	g_object_unref(base);
+	return code;
+#else
+	vips_error("bimg", "colour adjustments require libvips 8.0+");
+	return 1;
+#endif
+}
+
+static int
+vips_effects_grey(VipsImage *in, VipsImage **out) {
+	if (in->Bands < 3) {
+		return vips_copy(in, out, NULL);
+	}
+	return vips_colourspace(in, out, VIPS_INTERPRETATION_B_W, NULL);
+}
+
+static int
+vips_effects_rgb(VipsImage *in, VipsImage **out) {
+	if (in->Bands >= 3) {
+		return vips_copy(in, out, NULL);
+	}
+	return vips_colourspace(in, out, VIPS_INTERPRETATION_sRGB, NULL);
+}
+
+/**
+ * Applies the filter effects to the 8 bits sRGB version of the image,
+ * in a fixed order: greyscale, sepia, duotone, posterise, threshold
+ * and invert. The alpha channel is preserved.
+ */
+int
+vips_effects_bridge(VipsImage *in, VipsImage **out, int greyscale, int sepia, int duotone, double *shadow, double *highlight, int posterise, double threshold, int invert) {
+#if (VIPS_MAJOR_VERSION >= 8)
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 20);
+	VipsImage *colour, *alpha = NULL;
+
+	if (vips_srgb_bridge(in, &t[0])) {
+		g_object_unref(base);
+		return 1;
+	}
+	colour = t[0];
+
+	if (has_alpha_channel(t[0])) {
+		if (
+			vips_extract_band(t[0], &t[1], 0, "n", t[0]->Bands - 1, NULL) ||
+			vips_extract_band(t[0], &t[2], t[0]->Bands - 1, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[1];
+		alpha = t[2];
+	}
+
+	if (greyscale) {
+		if (vips_effects_grey(colour, &t[3])) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[3];
+	}
+
+	if (sepia) {
+		double matrix[9] = {
+			0.393, 0.769, 0.189,
+			0.349, 0.686, 0.168,
+			0.272, 0.534, 0.131
+		};
+
+		t[4] = vips_image_new_matrix_from_array(3, 3, matrix, 9);
+		if (
+			t[4] == NULL ||
+			vips_effects_rgb(colour, &t[5]) ||
+			vips_recomb(t[5], &t[6], t[4], NULL) ||
+			vips_cast(t[6], &t[7], VIPS_FORMAT_UCHAR, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[7];
+	}
+
+	if (duotone) {
+		// Maps the luminance linearly from the shadow to the highlight colour
+		double a[3], b[3];
+		for (int i = 0; i < 3; i++) {
+			a[i] = (highlight[i] - shadow[i]) / 255.0;
+			b[i] = shadow[i];
+		}
+
+		if (
+			vips_effects_grey(colour, &t[8]) ||
+			vips_linear(t[8], &t[9], a, b, 3, NULL) ||
+			vips_cast(t[9], &t[10], VIPS_FORMAT_UCHAR, NULL) ||
+			vips_copy(t[10], &t[11], "interpretation", VIPS_INTERPRETATION_sRGB, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[11];
+	}
+
+	if (posterise > 1) {
+		double levels = posterise - 1;
+
+		if (
+			vips_linear1(colour, &t[12], levels / 255.0, 0, NULL) ||
+			vips_rint(t[12], &t[13], NULL) ||
+			vips_linear1(t[13], &t[14], 255.0 / levels, 0, NULL) ||
+			vips_cast(t[14], &t[15], VIPS_FORMAT_UCHAR, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[15];
+	}
+
+	if (threshold > 0) {
+		if (
+			vips_effects_grey(colour, &t[16]) ||
+			vips_moreeq_const1(t[16], &t[17], threshold, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[17];
+	}
+
+	if (invert) {
+		if (vips_invert(colour, &t[18], NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[18];
+	}
+
+	if (alpha != NULL) {
+		if (vips_bandjoin2(colour, alpha, &t[19], NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		colour = t[19];
+	}
+
+	int code = vips_copy(colour, out, NULL);
+	g_object_unref(base);
+	return code;
+#else
+	vips_error("bimg", "effects require libvips 8.0+");
+	return 1;
+#endif
+}
+
+/**
+ * Finds the bounding box of the non-border pixels, whose distance to
+ * the background exceeds the threshold. The background is either the
+ * given RGB colour or, if autobg is set, the top-left pixel colour.
+ */
+int
+vips_find_trim_bridge(VipsImage *in, int *left, int *top, int *width, int *height, double threshold, double r, double g, double b, int autobg) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+	double max = in->BandFmt == VIPS_FORMAT_USHORT ? 65535 : 255;
+	double background[4] = { 0, 0, 0, max };
+	int n = in->Bands > 4 ? 4 : in->Bands;
+
+	// 16 bits images
+	if (max > 255) {
+		r *= 257;
+		g *= 257;
+		b *= 257;
+	}
+
+	if (autobg) {
+		double *point;
+		int bands;
+
+		if (vips_getpoint(in, &point, &bands, 0, 0, NULL)) {
+			return 1;
+		}
+		for (int i = 0; i < n && i < bands; i++) {
+			background[i] = point[i];
+		}
+		g_free(point);
+	} else if (n < 3) {
+		background[0] = (r + g + b) / 3;
+		background[1] = max;
+	} else {
+		background[0] = r;
+		background[1] = g;
+		background[2] = b;
+	}
+
+	VipsArrayDouble *array = vips_array_double_new(background, n);
+	int code = vips_find_trim(in, left, top, width, height, "threshold", threshold, "background", array, NULL);
+	vips_area_unref(VIPS_AREA(array));
+
+	return code;
+#else
+	vips_error("bimg", "trim requires libvips 8.6+");
+	return 1;
+#endif
+}
+
+/**
+ * Rotates the image by the given [a, b, c, d] rotation matrix.
+ * Uncovered areas are filled with the given colour, or transparent pixels.
+ * The canvas is expanded to fit the rotated image, unless crop is set,
+ * in which case the output keeps the input size and centre.
+ */
+int
+vips_rotate_matrix_bridge(VipsImage *in, VipsImage **out, double *matrix, double r, double g, double b, int transparent, int crop, VipsInterpolate *interpolator) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 5);
+	VipsImage *image = in;
+	double max = in->BandFmt == VIPS_FORMAT_USHORT ? 65535 : 255;
+	int alpha = has_alpha_channel(in);
+
+	if (transparent && !alpha) {
+		if (vips_bandjoin_const1(in, &t[0], max, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		image = t[0];
+		alpha = 1;
+	}
+
+	// Background of every band, with an opaque or transparent alpha
+	double background[5] = { r, g, b, 0, 0 };
+	int colours = alpha ? image->Bands - 1 : image->Bands;
+	if (colours < 3) {
+		background[0] = (r + g + b) / 3;
+	}
+	if (max > 255) {
+		for (int i = 0; i < colours; i++) {
+			background[i] *= 257;
+		}
+	}
+	if (alpha) {
+		background[colours] = transparent ? 0 : max;
+	}
+	// Premultiplied transparent pixels have no colour,
+	// which would otherwise fringe the rotated edges
+	if (transparent) {
+		for (int i = 0; i < colours; i++) {
+			background[i] = 0;
+		}
+	}
+
+	double a = matrix[0], bb = matrix[1], c = matrix[2], d = matrix[3];
+
+	VipsArrayDouble *bg = vips_array_double_new(background, image->Bands > 5 ? 5 : image->Bands);
+	VipsArrayInt *area = NULL;
+
+	if (crop) {
+		// Keep the input size, centred on the rotated input centre
+		double cx = a * in->Xsize / 2.0 + bb * in->Ysize / 2.0;
+		double cy = c * in->Xsize / 2.0 + d * in->Ysize / 2.0;
+		int oarea[4] = { (int) (cx - in->Xsize / 2.0), (int) (cy - in->Ysize / 2.0), in->Xsize, in->Ysize };
+		area = vips_array_int_new(oarea, 4);
+	}
+
+	int code;
+	if (alpha) {
+		code = vips_premultiply(image, &t[1], NULL) ||
+			vips_affine(t[1], &t[2], a, bb, c, d,
+				"interpolate", interpolator,
+				"extend", VIPS_EXTEND_BACKGROUND,
+				"background", bg,
+				"premultiplied", TRUE,
+				area ? "oarea" : NULL, area,
+				NULL) ||
+			vips_unpremultiply(t[2], &t[3], NULL) ||
+			vips_cast(t[3], out, image->BandFmt, NULL);
+	} else {
+		code = vips_affine(image, &t[4], a, bb, c, d,
+				"interpolate", interpolator,
+				"extend", VIPS_EXTEND_BACKGROUND,
+				"background", bg,
+				area ? "oarea" : NULL, area,
+				NULL) ||
+			vips_cast(t[4], out, image->BandFmt, NULL);
+	}
+
+	vips_area_unref(VIPS_AREA(bg));
+	if (area != NULL) {
+		vips_area_unref(VIPS_AREA(area));
+	}
+	g_object_unref(base);
+
+	return code;
+#else
+	vips_error("bimg", "rotation by an arbitrary angle requires libvips 8.6+");
+	return 1;
+#endif
+}
+
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+// Blend modes of the layers, in the order of the Go BlendMode constants
+static VipsBlendMode blend_modes[] = {
+	VIPS_BLEND_MODE_OVER,
+	VIPS_BLEND_MODE_MULTIPLY,
+	VIPS_BLEND_MODE_SCREEN,
+	VIPS_BLEND_MODE_OVERLAY,
+	VIPS_BLEND_MODE_DARKEN,
+	VIPS_BLEND_MODE_LIGHTEN,
+	VIPS_BLEND_MODE_COLOUR_DODGE,
+	VIPS_BLEND_MODE_COLOUR_BURN,
+	VIPS_BLEND_MODE_HARD_LIGHT,
+	VIPS_BLEND_MODE_SOFT_LIGHT,
+	VIPS_BLEND_MODE_DIFFERENCE,
+	VIPS_BLEND_MODE_EXCLUSION,
+	VIPS_BLEND_MODE_ADD,
+	VIPS_BLEND_MODE_SATURATE
+};
+
+/**
+ * Converts the overlay to sRGB with an alpha channel,
+ * multiplied by the opacity.
+ */
+static int
+prepare_overlay(VipsImage *sub, VipsImage **out, double opacity) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 4);
+	VipsImage *overlay;
+
+	if (vips_srgb_bridge(sub, &t[0])) {
+		g_object_unref(base);
+		return 1;
+	}
+	overlay = t[0];
+
+	if (!has_alpha_channel(overlay)) {
+		if (vips_bandjoin_const1(overlay, &t[1], 255, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		overlay = t[1];
+	}
+
+	if (opacity < 1) {
+		double a[4] = { 1, 1, 1, opacity };
+		double b[4] = { 0, 0, 0, 0 };
+
+		if (
+			vips_linear(overlay, &t[2], a, b, 4, NULL) ||
+			vips_cast(t[2], &t[3], VIPS_FORMAT_UCHAR, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		overlay = t[3];
+	}
+
+	int code = vips_copy(overlay, out, NULL);
+	g_object_unref(base);
+	return code;
+}
+
+/**
+ * Blends the overlay, already embedded to the image size, over the image
+ * converted to sRGB, returning an 8 bits image. Opaque images stay opaque.
+ */
+static int
+composite_overlay(VipsImage *in, VipsImage *overlay, VipsImage **out, VipsBlendMode mode) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+	VipsImage *composite;
+
+	if (
+		vips_srgb_bridge(in, &t[0]) ||
+		vips_composite2(t[0], overlay, &t[1], mode, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+	composite = t[1];
+
+	if (!has_alpha_channel(t[0])) {
+		if (vips_extract_band(t[1], &t[2], 0, "n", t[1]->Bands - 1, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		composite = t[2];
+	}
+
+	int code = vips_cast(composite, out, VIPS_FORMAT_UCHAR, NULL);
+	g_object_unref(base);
+	return code;
+}
+#endif
+
+/**
+ * Overlays the watermark image over the base image, either once at the
+ * given position or tiled from it with the given spacing between tiles.
+ * The watermark alpha channel is multiplied by the opacity and the layers
+ * are blended with vips_composite, returning an 8 bits sRGB image.
+ */
+int
+vips_watermark_image_bridge(VipsImage *in, VipsImage *sub, VipsImage **out, int left, int top, double opacity, int tile, int spacing) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 4);
+
+	if (prepare_overlay(sub, &t[0], opacity)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	if (tile) {
+		// Tiles are separated by transparent spacing and start at the given position
+		int width = t[0]->Xsize + spacing;
+		int height = t[0]->Ysize + spacing;
+		int x = ((-left % width) + width) % width;
+		int y = ((-top % height) + height) % height;
+
+		if (
+			vips_embed(t[0], &t[1], 0, 0, width, height, NULL) ||
+			vips_replicate(t[1], &t[2], in->Xsize / width + 2, in->Ysize / height + 2, NULL) ||
+			vips_extract_area(t[2], &t[3], x, y, in->Xsize, in->Ysize, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+	} else if (vips_embed(t[0], &t[3], left, top, in->Xsize, in->Ysize, NULL)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	int code = composite_overlay(in, t[3], out, VIPS_BLEND_MODE_OVER);
+	g_object_unref(base);
+	return code;
+#else
+	vips_error("bimg", "image watermarks require libvips 8.6+");
+	return 1;
+#endif
+}
+
+/**
+ * Composites a layer over the image at the given position with the blend mode,
+ * one of the Go BlendMode constants.
+ * Both images are converted to sRGB, so greyscale and colour layers can be mixed.
+ */
+int
+vips_composite_layer_bridge(VipsImage *in, VipsImage *sub, VipsImage **out, int mode, int left, int top, double opacity) {
+#if (VIPS_MAJOR_VERSION > 8 || (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 6))
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 2);
+
+	if (mode < 0 || mode >= (int) (sizeof(blend_modes) / sizeof(blend_modes[0]))) {
+		vips_error("bimg", "Unsupported blend mode: %d", mode);
+		g_object_unref(base);
+		return 1;
+	}
+
+	if (
+		prepare_overlay(sub, &t[0], opacity) ||
+		vips_embed(t[0], &t[1], left, top, in->Xsize, in->Ysize, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	int code = composite_overlay(in, t[1], out, blend_modes[mode]);
+	g_object_unref(base);
+	return code;
+#else
+	vips_error("bimg", "compositing requires libvips 8.6+");
+	return 1;
+#endif
+}
+
+/**
+ * Renders the image into memory, so it can be encoded or
+ * compared repeatedly without running its operations again.
+ */
+int
+vips_copy_memory_bridge(VipsImage *in, VipsImage **out) {
+	*out = vips_image_copy_memory(in);
+	return *out == NULL ? 1 : 0;
+}
+
+int
+vips_extract_band_bridge(VipsImage *in, VipsImage **out, int band, int n) {
+	return vips_extract_band(in, out, band, "n", n, NULL);
+}
+
+int
+vips_bandjoin_bridge(VipsImage **in, VipsImage **out, int n) {
+	return vips_bandjoin(in, out, n, NULL);
+}
+
+int
+vips_bandbool_bridge(VipsImage *in, VipsImage **out, int operation) {
+	return vips_bandbool(in, out, operation, NULL);
+}
+
+/**
+ * Sets the interpretation matching the number of bands of the image,
+ * as band operations keep the interpretation of their input.
+ */
+int
+vips_bands_interpretation_bridge(VipsImage *in, VipsImage **out) {
+	int ushort = in->BandFmt == VIPS_FORMAT_USHORT;
+	VipsInterpretation interpretation = VIPS_INTERPRETATION_MULTIBAND;
+
+	if (in->Bands <= 2) {
+		interpretation = ushort ? VIPS_INTERPRETATION_GREY16 : VIPS_INTERPRETATION_B_W;
+	} else if (in->Bands <= 4) {
+		interpretation = ushort ? VIPS_INTERPRETATION_RGB16 : VIPS_INTERPRETATION_sRGB;
+	}
+
+	return vips_copy(in, out, "interpretation", interpretation, NULL);
+}
+
+/**
+ * Joins the bands of the image in the given order,
+ * which may skip or repeat bands.
+ */
+int
+vips_reorder_bands_bridge(VipsImage *in, VipsImage **out, int *order, int n) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), n);
+
+	for (int i = 0; i < n; i++) {
+		if (vips_extract_band(in, &t[i], order[i], NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+	}
+
+	int code = vips_bandjoin(t, out, n, NULL);
+	g_object_unref(base);
+	return code;
+}
+
+/**
+ * Flattens the alpha channel of the image onto the background colour.
+ * Greyscale images use the luminance of the colour.
+ */
+int
+vips_flatten_bridge(VipsImage *in, VipsImage **out, double r, double g, double b) {
+	if (!has_alpha_channel(in)) {
+		return vips_copy(in, out, NULL);
+	}
+
+	double background[3] = { r, g, b };
+	double max_alpha = 255;
+	int bands = in->Bands - 1;
+
+	if (bands < 3) {
+		background[0] = (r + g + b) / 3;
+	}
+	if (in->BandFmt == VIPS_FORMAT_USHORT) {
+		max_alpha = 65535;
+		for (int i = 0; i < 3; i++) {
+			background[i] *= 257;
+		}
+	}
+
+	VipsImage *flat;
+	VipsArrayDouble *array = vips_array_double_new(background, bands < 3 ? 1 : 3);
+	int code = vips_flatten(in, &flat, "background", array, "max_alpha", max_alpha, NULL);
+	vips_area_unref(VIPS_AREA(array));
+	if (code) {
+		return 1;
+	}
+
+	code = vips_cast(flat, out, in->BandFmt, NULL);
+	g_object_unref(flat);
+	return code;
+}
+
+/**
+ * Adds an opaque alpha channel, removes the alpha channel or extracts it
+ * as a greyscale image, which is opaque if the image had no alpha.
+ */
+int
+vips_alpha_bridge(VipsImage *in, VipsImage **out, int operation) {
+	double max_alpha = in->BandFmt == VIPS_FORMAT_USHORT ? 65535 : 255;
+	int alpha = has_alpha_channel(in);
+
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+	int code;
+
+	switch (operation) {
+	case 1:
+		if (alpha) {
+			code = vips_copy(in, out, NULL);
+		} else {
+			code = vips_bandjoin_const1(in, &t[0], max_alpha, NULL) ||
+				vips_cast(t[0], out, in->BandFmt, NULL);
+		}
+		break;
+	case 2:
+		if (alpha) {
+			code = vips_extract_band(in, out, 0, "n", in->Bands - 1, NULL);
+		} else {
+			code = vips_copy(in, out, NULL);
+		}
+		break;
+	case 3:
+		if (alpha) {
+			code = vips_extract_band(in, &t[1], in->Bands - 1, NULL);
+		} else {
+			code = vips_black(&t[0], in->Xsize, in->Ysize, NULL) ||
+				vips_linear1(t[0], &t[1], 1, max_alpha, NULL);
+		}
+		code = code ||
+			vips_cast(t[1], &t[2], in->BandFmt, NULL) ||
+			vips_copy(t[2], out, "interpretation",
+				in->BandFmt == VIPS_FORMAT_USHORT ? VIPS_INTERPRETATION_GREY16 : VIPS_INTERPRETATION_B_W, NULL);
+		break;
+	default:
+		code = vips_copy(in, out, NULL);
+	}
+
+	g_object_unref(base);
+	return code;
+}
+
+/**
+ * Extracts a one band alpha mask from an image: its alpha channel
+ * if it's transparent, otherwise its luminance.
+ */
+int
+vips_alpha_mask_bridge(VipsImage *in, VipsImage **out) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 3);
+	VipsImage *mask;
+
+	if (has_alpha_channel(in)) {
+		if (vips_extract_band(in, &t[0], in->Bands - 1, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		mask = t[0];
+
+		// 16 bits alpha
+		if (mask->BandFmt == VIPS_FORMAT_USHORT) {
+			if (vips_linear1(mask, &t[1], 1.0 / 257, 0, NULL)) {
+				g_object_unref(base);
+				return 1;
+			}
+			mask = t[1];
+		}
+	} else {
+		if (vips_colourspace_issupported(in)) {
+			if (
+				vips_colourspace(in, &t[0], VIPS_INTERPRETATION_B_W, NULL) ||
+				vips_extract_band(t[0], &t[1], 0, NULL)
+			) {
+				g_object_unref(base);
+				return 1;
+			}
+		} else if (vips_extract_band(in, &t[1], 0, NULL)) {
+			g_object_unref(base);
+			return 1;
+		}
+		mask = t[1];
+	}
+
+	int code = vips_cast(mask, out, VIPS_FORMAT_UCHAR, NULL);
+	g_object_unref(base);
+	return code;
+}
+
+/**
+ * Multiplies the alpha channel of the image by the one band mask,
+ * cropped or extended to the image size. The result is flattened
+ * onto the background colour if requested.
+ */
+int
+vips_mask_bridge(VipsImage *in, VipsImage *mask, VipsImage **out, int flatten, double r, double g, double b) {
+	VipsImage *base = vips_image_new();
+	VipsImage **t = (VipsImage **) vips_object_local_array(VIPS_OBJECT(base), 9);
+	VipsImage *alpha;
+
+	if (
+		vips_srgb_bridge(in, &t[0]) ||
+		vips_embed(mask, &t[1], 0, 0, t[0]->Xsize, t[0]->Ysize, "extend", VIPS_EXTEND_COPY, NULL) ||
+		vips_extract_band(t[0], &t[2], 0, "n", 3, NULL)
+	) {
+		g_object_unref(base);
+		return 1;
+	}
+	alpha = t[1];
+
+	if (has_alpha_channel(t[0])) {
+		if (
+			vips_extract_band(t[0], &t[3], 3, NULL) ||
+			vips_multiply(t[3], t[1], &t[4], NULL) ||
+			vips_linear1(t[4], &t[5], 1.0 / 255, 0, NULL) ||
+			vips_cast(t[5], &t[6], VIPS_FORMAT_UCHAR, NULL)
+		) {
+			g_object_unref(base);
+			return 1;
+		}
+		alpha = t[6];
+	}
+
+	if (vips_bandjoin2(t[2], alpha, &t[7], NULL)) {
+		g_object_unref(base);
+		return 1;
+	}
+
+	if (!flatten) {
+		int code = vips_copy(t[7], out, NULL);
+		g_object_unref(base);
+		return code;
+	}
+
+	double background[3] = { r, g, b };
+	VipsArrayDouble *array = vips_array_double_new(background, 3);
+	int code = vips_flatten(t[7], &t[8], "background", array, NULL) ||
+		vips_cast(t[8], out, VIPS_FORMAT_UCHAR, NULL);
+	vips_area_unref(VIPS_AREA(array));
+
+	g_object_unref(base);
+	return code;
+}
@@ -93,6 +93,22 @@ func TestVipsWatermark(t *testing.T) {
 	}
 }
 
+func TestVipsSSIM(t *testing.T) {
+	image, _, err := vipsRead(readImage("test.jpg"))
+	if err != nil {
+		t.Fatalf("Cannot read the image: %s", err)
+	}
+	defer vipsUnref(image)
+
+	ssim, err := vipsSSIM(image, image)
+	if err != nil {
+		t.Fatalf("Cannot compute SSIM: %s", err)
+	}
+	if ssim < 0.999 {
+		t.Fatalf("Invalid SSIM for identical images: %f", ssim)
+	}
+}
+
 func TestVipsImageType(t *testing.T) {
 	imgType := vipsImageType(readImage("test.jpg"))
 	if imgType != JPEG {